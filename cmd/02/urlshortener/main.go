@@ -1,6 +1,8 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 
@@ -12,7 +14,34 @@ import (
 const addr = "localhost:8080"
 
 func main() {
-	srv := urlshortener.NewShortener("http://" + addr)
+	storageType := flag.String("storage", "memory", "where links are kept: memory, bolt or file")
+	path := flag.String("path", "urlshortener.db", "database file for bolt, log file for file storage")
+	codes := flag.String("codes", "hash", "how short codes are made: hash, random or counter")
+	codeLength := flag.Int("code-length", urlshortener.DefaultCodeLength, "length of short codes")
+	alphabet := flag.String("alphabet", urlshortener.Base62Alphabet, "characters of short codes")
+	flag.Parse()
+
+	generator, err := newGenerator(*codes, *codeLength, *alphabet)
+	if err != nil {
+		log.Fatalf("Failed to create code generator: %v", err)
+	}
+
+	storage, err := openStorage(*storageType, *path)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer func() {
+		_ = storage.Close()
+	}()
+	// the counter is not persisted, it continues after the stored links
+	if counter, ok := generator.(*urlshortener.CounterGenerator); ok {
+		count, err := storage.Len()
+		if err != nil {
+			log.Fatalf("Failed to count links: %v", err)
+		}
+		counter.Start(uint64(count))
+	}
+	srv := urlshortener.NewShortenerWithStorage("http://"+addr, storage, urlshortener.WithGenerator(generator))
 
 	r := chi.NewMux()
 	r.Put("/save", srv.HandleSave)
@@ -22,3 +51,27 @@ func main() {
 		log.Fatalf("HTTP server error: %v", err)
 	}
 }
+
+func openStorage(storageType string, path string) (urlshortener.Storage, error) {
+	switch storageType {
+	case "memory":
+		return urlshortener.NewShardedStorage(0), nil
+	case "bolt":
+		return urlshortener.NewBoltStorage(path)
+	case "file":
+		return urlshortener.NewFileStorage(path)
+	}
+	return nil, fmt.Errorf("unknown storage %q, expected memory, bolt or file", storageType)
+}
+
+func newGenerator(codes string, length int, alphabet string) (urlshortener.CodeGenerator, error) {
+	switch codes {
+	case "hash":
+		return urlshortener.NewHashGenerator(length, alphabet)
+	case "random":
+		return urlshortener.NewRandomGenerator(length, alphabet)
+	case "counter":
+		return urlshortener.NewCounterGenerator(length, alphabet)
+	}
+	return nil, fmt.Errorf("unknown codes %q, expected hash, random or counter", codes)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+const usage = `Usage:
+  jwt encode [--alg HS256] --key-file FILE [--ttl 1h | --exp 2022-01-02T15:04:05Z] [--kid ID] [--flat] [PAYLOAD_FILE]
+  jwt decode [--alg HS256] --key-file FILE [--iss ISSUER] [--aud AUDIENCE] [--leeway 30s] [TOKEN]
+  jwt verify (the same as decode)
+  jwt inspect [TOKEN]
+
+Payload and token are read from stdin when omitted or "-".
+The key file is the HMAC secret (a trailing newline is dropped) or a PEM key:
+private for encode, public or private for decode.`
+
+const (
+	exitOK    = 0
+	exitUsage = 1
+)
+
+// exit code of decode/verify for every sentinel, the first match wins
+var exitCodes = []struct {
+	Err  error
+	Code int
+}{
+	{jwt.ErrSignatureInvalid, 10},
+	{jwt.ErrSignMethodMismatched, 11},
+	{jwt.ErrInvalidSignMethod, 12},
+	{jwt.ErrInvalidKey, 13},
+	{jwt.ErrUnknownKeyID, 14},
+	{jwt.ErrTokenExpired, 20},
+	{jwt.ErrTokenNotValidYet, 21},
+	{jwt.ErrTokenUsedBeforeIssued, 22},
+	{jwt.ErrInvalidIssuer, 23},
+	{jwt.ErrInvalidSubject, 24},
+	{jwt.ErrSubjectMissing, 25},
+	{jwt.ErrInvalidAudience, 26},
+	{jwt.ErrTokenRevoked, 27},
+	{jwt.ErrInvalidToken, 30},
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+	var err error
+	switch os.Args[1] {
+	case "encode":
+		err = encode(os.Args[2:])
+	case "decode", "verify":
+		os.Exit(decode(os.Args[2:]))
+	case "inspect":
+		err = inspect(os.Args[2:])
+	default:
+		log.Fatal(usage)
+	}
+	if err != nil {
+		log.Fatalf("jwt %s: %v", os.Args[1], err)
+	}
+}
+
+func encode(args []string) error {
+	flags := flag.NewFlagSet("encode", flag.ExitOnError)
+	alg := flags.String("alg", string(jwt.HS256), "signing algorithm")
+	keyFile := flags.String("key-file", "", "HMAC secret or PEM private key")
+	ttl := flags.Duration("ttl", 0, "token lifetime")
+	exp := flags.String("exp", "", "expiration time, RFC 3339")
+	kid := flags.String("kid", "", "key ID header")
+	flat := flags.Bool("flat", false, "payload is the claim set itself, not {\"d\": payload}")
+	_ = flags.Parse(args)
+
+	opts, err := keyOptions(jwt.SignMethod(*alg), *keyFile, true)
+	if err != nil {
+		return err
+	}
+	if *ttl != 0 {
+		opts = append(opts, jwt.WithTTL(*ttl))
+	}
+	if *exp != "" {
+		expires, err := time.Parse(time.RFC3339, *exp)
+		if err != nil {
+			return fmt.Errorf("invalid --exp: %w", err)
+		}
+		opts = append(opts, jwt.WithExpires(expires))
+	}
+	if *kid != "" {
+		opts = append(opts, jwt.WithKeyID(*kid))
+	}
+	if *flat {
+		opts = append(opts, jwt.WithFlatClaims())
+	}
+
+	payload, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !json.Valid(payload) {
+		return errors.New("payload is not JSON")
+	}
+	token, err := jwt.Encode(json.RawMessage(payload), opts...)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(token))
+	return nil
+}
+
+// returns the exit code
+func decode(args []string) int {
+	flags := flag.NewFlagSet("decode", flag.ExitOnError)
+	alg := flags.String("alg", string(jwt.HS256), "expected signing algorithm")
+	keyFile := flags.String("key-file", "", "HMAC secret or PEM public (or private) key")
+	issuer := flags.String("iss", "", "expected issuer")
+	audience := flags.String("aud", "", "expected audience")
+	leeway := flags.Duration("leeway", 0, "allowed clock skew")
+	_ = flags.Parse(args)
+
+	opts, err := keyOptions(jwt.SignMethod(*alg), *keyFile, false)
+	if err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	opts = append(opts, jwt.WithLeeway(*leeway))
+	if *issuer != "" {
+		opts = append(opts, jwt.WithIssuer(*issuer))
+	}
+	if *audience != "" {
+		opts = append(opts, jwt.WithAudience(*audience))
+	}
+
+	token, err := readToken(flags.Arg(0))
+	if err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	parsed, err := jwt.Parse(token)
+	if err == nil {
+		err = printToken(parsed)
+	}
+	if err == nil {
+		_, err = parsed.Verify(opts...)
+	}
+	if err != nil {
+		fmt.Printf("verification failed: %v\n", err)
+		return exitCode(err)
+	}
+	fmt.Println("verification: OK")
+	return exitOK
+}
+
+func exitCode(err error) int {
+	for _, code := range exitCodes {
+		if errors.Is(err, code.Err) {
+			return code.Code
+		}
+	}
+	return exitUsage
+}
+
+func inspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	_ = flags.Parse(args)
+	token, err := readToken(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	parsed, err := jwt.Parse(token)
+	if err != nil {
+		return err
+	}
+	return printToken(parsed)
+}
+
+// prints header and claims as is, nothing is verified
+func printToken(parsed *jwt.UnverifiedToken) error {
+	header, err := json.MarshalIndent(parsed.Header, "", "  ")
+	if err != nil {
+		return err
+	}
+	var claims bytes.Buffer
+	if err := json.Indent(&claims, parsed.RawClaims, "", "  "); err != nil {
+		return err
+	}
+	fmt.Printf("Header:\n%s\nClaims:\n%s\n", header, claims.String())
+	return nil
+}
+
+func keyOptions(method jwt.SignMethod, keyFile string, private bool) ([]jwt.Option, error) {
+	if keyFile == "" {
+		return nil, errors.New("--key-file is required")
+	}
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	opts := []jwt.Option{jwt.WithSignMethod(method)}
+	switch method {
+	case jwt.HS256, jwt.HS512:
+		return append(opts, jwt.WithKey(bytes.TrimRight(key, "\r\n"))), nil
+	}
+	if !private {
+		if publicKey, err := jwt.ParsePublicKeyPEM(key); err == nil {
+			return append(opts, jwt.WithPublicKey(publicKey)), nil
+		}
+	}
+	privateKey, err := jwt.ParsePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, jwt.WithPrivateKey(privateKey)), nil
+}
+
+// the argument itself, or stdin for "" and "-"
+func readToken(arg string) ([]byte, error) {
+	if arg != "" && arg != "-" {
+		return []byte(arg), nil
+	}
+	token, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(token), nil
+}
+
+// a file, or stdin for "" and "-"
+func readInput(arg string) ([]byte, error) {
+	if arg == "" || arg == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(arg)
+}
@@ -0,0 +1,40 @@
+package urlshortener
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const maxAliasLength = 64
+
+var ErrInvalidAlias = errors.New("invalid alias")
+
+// paths of the service itself, an alias can't shadow them
+var defaultReservedAliases = []string{"save"}
+
+// WithReservedAliases forbids more aliases in addition to "save", e.g. paths of other handlers
+// on the same router. Reserved words are compared case-insensitively.
+func WithReservedAliases(words ...string) Option {
+	return func(s *URLShortener) {
+		for _, word := range words {
+			s.reserved[strings.ToLower(word)] = true
+		}
+	}
+}
+
+// an alias is 1 to maxAliasLength isCodeChar characters and not a reserved word
+func (s *URLShortener) checkAlias(alias string) error {
+	if len(alias) == 0 || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: length %d is out of [1, %d]", ErrInvalidAlias, len(alias), maxAliasLength)
+	}
+	for _, c := range []byte(alias) {
+		if !isCodeChar(c) {
+			return fmt.Errorf("%w: character %q is not allowed", ErrInvalidAlias, c)
+		}
+	}
+	if s.reserved[strings.ToLower(alias)] {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package urlshortener
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAlias(t *testing.T) {
+	srv := NewShortener("", WithReservedAliases("API"))
+	for _, alias := range []string{"spring-sale", "Spring_Sale_2022", "a", strings.Repeat("x", maxAliasLength)} {
+		require.NoError(t, srv.checkAlias(alias), alias)
+	}
+	for _, alias := range []string{"", strings.Repeat("x", maxAliasLength+1), "spring sale", "spring/sale",
+		"..", "распродажа", "save", "SAVE", "api"} {
+		require.ErrorIs(t, srv.checkAlias(alias), ErrInvalidAlias, alias)
+	}
+}
+
+func TestURLShortener_Alias(t *testing.T) {
+	srv := NewShortener("")
+	r := chi.NewMux()
+	r.Put("/save", srv.HandleSave)
+	r.Get("/{key}", srv.HandleExpand)
+	save := func(target string, alias string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		query := url.Values{"u": {target}, "alias": {alias}}
+		r.ServeHTTP(rw, httptest.NewRequest(http.MethodPut, "/save?"+query.Encode(), nil))
+		return rw
+	}
+
+	rw := save("https://shop.ru/sale", "spring-sale")
+	require.Equal(t, http.StatusCreated, rw.Code)
+	require.Equal(t, "/spring-sale", rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/spring-sale", nil))
+	require.Equal(t, http.StatusMovedPermanently, rw.Code)
+	require.Equal(t, "https://shop.ru/sale", rw.Header().Get("Location"))
+
+	// a retry is idempotent, another URL conflicts
+	rw = save("https://shop.ru/sale", "spring-sale")
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.Equal(t, LinkExisting, rw.Header().Get(LinkStatusHeader))
+	require.Equal(t, http.StatusConflict, save("https://evil.com", "spring-sale").Code)
+
+	require.Equal(t, http.StatusBadRequest, save("https://shop.ru", "save").Code)
+	require.Equal(t, http.StatusBadRequest, save("https://shop.ru", "spring/sale").Code)
+
+	// aliases and generated codes share one namespace
+	code := strings.TrimPrefix(save("https://yandex.ru", "").Body.String(), "/")
+	require.Equal(t, http.StatusConflict, save("https://google.com", code).Code)
+}
+
+// a generated code which is taken by an alias is skipped
+func TestURLShortener_AliasTakesCode(t *testing.T) {
+	generator, err := NewHashGenerator(DefaultCodeLength, Base62Alphabet)
+	require.NoError(t, err)
+	code, err := generator.Generate("https://yandex.ru", 0)
+	require.NoError(t, err)
+
+	srv := NewShortener("", WithGenerator(generator))
+	rw := httptest.NewRecorder()
+	srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/save?u=https://shop.ru&alias="+code, nil))
+	require.Equal(t, http.StatusCreated, rw.Code)
+
+	rw = httptest.NewRecorder()
+	srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/save?u="+url.QueryEscape("https://yandex.ru"), nil))
+	require.Equal(t, http.StatusCreated, rw.Code)
+	require.NotEqual(t, "/"+code, rw.Body.String())
+}
@@ -0,0 +1,109 @@
+package urlshortener
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	linksBucket = []byte("links")
+	// url -> the first key saved for it
+	urlsBucket = []byte("urls")
+)
+
+// BoltStorage keeps links in an embedded bbolt database file
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	// the file is locked by one process, don't hang if another one holds it
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		links, err := tx.CreateBucketIfNotExists(linksBucket)
+		if err != nil {
+			return err
+		}
+		if tx.Bucket(urlsBucket) != nil {
+			return nil
+		}
+		// databases created before the index get it built once
+		urls, err := tx.CreateBucket(urlsBucket)
+		if err != nil {
+			return err
+		}
+		return links.ForEach(func(key, url []byte) error {
+			if len(url) == 0 || urls.Get(url) != nil {
+				return nil
+			}
+			return urls.Put(url, key)
+		})
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &BoltStorage{db: db}, nil
+}
+
+func (s *BoltStorage) Save(key string, url string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(linksBucket)
+		if bucket.Get([]byte(key)) != nil {
+			return ErrKeyExists
+		}
+		if err := bucket.Put([]byte(key), []byte(url)); err != nil {
+			return err
+		}
+		// bbolt keys can't be empty, such a link is just not indexed
+		urls := tx.Bucket(urlsBucket)
+		if url == "" || urls.Get([]byte(url)) != nil {
+			return nil
+		}
+		return urls.Put([]byte(url), []byte(key))
+	})
+}
+
+func (s *BoltStorage) Load(key string) (string, error) {
+	var url string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(linksBucket).Get([]byte(key))
+		if value == nil {
+			return ErrNotFound
+		}
+		// the value is valid only inside the transaction
+		url = string(value)
+		return nil
+	})
+	return url, err
+}
+
+func (s *BoltStorage) Find(url string) (string, error) {
+	var key string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(urlsBucket).Get([]byte(url))
+		if value == nil {
+			return ErrNotFound
+		}
+		key = string(value)
+		return nil
+	})
+	return key, err
+}
+
+func (s *BoltStorage) Len() (int, error) {
+	var count int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(linksBucket).Stats().KeyN
+		return nil
+	})
+	return count, err
+}
+
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,179 @@
+package urlshortener
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+type linkRecord struct {
+	Key string `json:"k"`
+	URL string `json:"u"`
+}
+
+// FileStorage is MemoryStorage persisted to an append-only log of JSON lines. The log
+// is read into memory on open and compacted if it has garbage (torn or duplicate lines).
+type FileStorage struct {
+	memory *MemoryStorage
+
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func NewFileStorage(path string) (*FileStorage, error) {
+	s := &FileStorage{memory: NewMemoryStorage(), path: path}
+	garbage, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if garbage {
+		if err := s.compact(); err != nil {
+			return nil, err
+		}
+	}
+	s.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reports whether the log has lines which are not needed
+func (s *FileStorage) load() (bool, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	garbage := false
+	// not bufio.Scanner: a line is as long as its URL, the log must open whatever was saved
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var record linkRecord
+			// a torn last line after a crash is skipped
+			if json.Unmarshal(line, &record) != nil || record.Key == "" {
+				garbage = true
+			} else if s.memory.Save(record.Key, record.URL) != nil {
+				garbage = true
+			}
+			// the next append would be glued to it
+			if line[len(line)-1] != '\n' {
+				garbage = true
+			}
+		}
+		if err == io.EOF {
+			return garbage, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func (s *FileStorage) Save(key string, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// saves are serialized by s.mu, so the key can't be taken between the check and memory.Save;
+	// the link is served only once it is on disk
+	if _, err := s.memory.Load(key); err == nil {
+		return ErrKeyExists
+	}
+	if err := writeLinkRecord(s.file, key, url); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	return s.memory.Save(key, url)
+}
+
+func (s *FileStorage) Load(key string) (string, error) {
+	return s.memory.Load(key)
+}
+
+func (s *FileStorage) Find(url string) (string, error) {
+	return s.memory.Find(url)
+}
+
+func (s *FileStorage) Len() (int, error) {
+	return s.memory.Len()
+}
+
+// Compact rewrites the log with one line per link, the new file replaces the old one atomically
+func (s *FileStorage) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.compact(); err != nil {
+		return err
+	}
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	var err error
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	return err
+}
+
+func (s *FileStorage) compact() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	s.memory.mu.RLock()
+	// the key Find returns for a URL goes first, so that it is still the first one on reopen
+	for url, key := range s.memory.keys {
+		if err = writeLinkRecord(writer, key, url); err != nil {
+			break
+		}
+	}
+	for key, url := range s.memory.mapping {
+		if err != nil {
+			break
+		}
+		if s.memory.keys[url] != key {
+			err = writeLinkRecord(writer, key, url)
+		}
+	}
+	s.memory.mu.RUnlock()
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func writeLinkRecord(w io.Writer, key string, url string) error {
+	line, err := json.Marshal(linkRecord{Key: key, URL: url})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
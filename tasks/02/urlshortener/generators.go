@@ -0,0 +1,154 @@
+package urlshortener
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	Base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	DefaultCodeLength = 7
+	// enough for every alphabet to fit into a sha256 digest
+	maxCodeLength = 32
+)
+
+var ErrInvalidGenerator = errors.New("invalid code generator parameters")
+
+// CodeGenerator makes short codes for links. attempt is 0 for the first code of a URL and
+// grows while the returned codes are already taken, so a generator can probe further.
+type CodeGenerator interface {
+	Generate(url string, attempt int) (string, error)
+}
+
+// checks the code length and that the alphabet is at least 2 distinct isCodeChar characters
+func checkCodeParams(length int, alphabet string) error {
+	if length < 1 || length > maxCodeLength {
+		return fmt.Errorf("%w: code length %d is out of [1, %d]", ErrInvalidGenerator, length, maxCodeLength)
+	}
+	if len(alphabet) < 2 {
+		return fmt.Errorf("%w: alphabet %q is shorter than 2 characters", ErrInvalidGenerator, alphabet)
+	}
+	for i, c := range []byte(alphabet) {
+		if !isCodeChar(c) {
+			return fmt.Errorf("%w: alphabet character %q is not allowed", ErrInvalidGenerator, c)
+		}
+		if strings.IndexByte(alphabet[:i], c) >= 0 {
+			return fmt.Errorf("%w: alphabet character %q is repeated", ErrInvalidGenerator, c)
+		}
+	}
+	return nil
+}
+
+// letters, digits, '-' and '_' need no escaping in a path
+func isCodeChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '-' || c == '_'
+}
+
+// CounterGenerator encodes a sequence number, so codes are as short as possible but easy
+// to guess. Codes are padded to length and grow beyond it when the sequence runs out.
+// The sequence is not persisted: after a restart with a persistent storage it must be
+// continued with Start(storage.Len()), otherwise taken codes are skipped one by one.
+type CounterGenerator struct {
+	length   int
+	alphabet string
+	next     uint64
+}
+
+func NewCounterGenerator(length int, alphabet string) (*CounterGenerator, error) {
+	if err := checkCodeParams(length, alphabet); err != nil {
+		return nil, err
+	}
+	return &CounterGenerator{length: length, alphabet: alphabet}, nil
+}
+
+// Start sets the next sequence number, e.g. the number of stored links after a restart
+func (g *CounterGenerator) Start(next uint64) {
+	atomic.StoreUint64(&g.next, next)
+}
+
+func (g *CounterGenerator) Generate(url string, attempt int) (string, error) {
+	n := atomic.AddUint64(&g.next, 1) - 1
+	base := uint64(len(g.alphabet))
+	code := make([]byte, 0, g.length)
+	for n > 0 || len(code) < g.length {
+		code = append(code, g.alphabet[n%base])
+		n /= base
+	}
+	// most significant digit first, so codes of one length sort as numbers
+	for i, j := 0, len(code)-1; i < j; i, j = i+1, j-1 {
+		code[i], code[j] = code[j], code[i]
+	}
+	return string(code), nil
+}
+
+// RandomGenerator picks every character uniformly with crypto/rand, a taken code
+// is just generated again
+type RandomGenerator struct {
+	length   int
+	alphabet string
+}
+
+func NewRandomGenerator(length int, alphabet string) (*RandomGenerator, error) {
+	if err := checkCodeParams(length, alphabet); err != nil {
+		return nil, err
+	}
+	return &RandomGenerator{length: length, alphabet: alphabet}, nil
+}
+
+func (g *RandomGenerator) Generate(url string, attempt int) (string, error) {
+	// bytes from limit up are rejected, otherwise the first characters would be more likely
+	limit := 256 - 256%len(g.alphabet)
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length)
+	for len(code) < g.length {
+		if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < g.length {
+				code = append(code, g.alphabet[int(b)%len(g.alphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// HashGenerator derives the code from sha256 of the URL, so the same URL always gets
+// the same first code. On a collision attempt k hashes the URL with k appended, which
+// gives every URL its own deterministic sequence of probes.
+type HashGenerator struct {
+	length   int
+	alphabet string
+}
+
+func NewHashGenerator(length int, alphabet string) (*HashGenerator, error) {
+	if err := checkCodeParams(length, alphabet); err != nil {
+		return nil, err
+	}
+	return &HashGenerator{length: length, alphabet: alphabet}, nil
+}
+
+func (g *HashGenerator) Generate(url string, attempt int) (string, error) {
+	input := url
+	if attempt > 0 {
+		input += "#" + strconv.Itoa(attempt)
+	}
+	digest := sha256.Sum256([]byte(input))
+	n := new(big.Int).SetBytes(digest[:])
+	base := big.NewInt(int64(len(g.alphabet)))
+	digit := new(big.Int)
+	code := make([]byte, g.length)
+	for i := range code {
+		n.DivMod(n, base, digit)
+		code[i] = g.alphabet[digit.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,188 @@
+package urlshortener
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeGenerators(t *testing.T) {
+	for name, factory := range map[string]func(int, string) (CodeGenerator, error){
+		"counter": func(length int, alphabet string) (CodeGenerator, error) { return NewCounterGenerator(length, alphabet) },
+		"random":  func(length int, alphabet string) (CodeGenerator, error) { return NewRandomGenerator(length, alphabet) },
+		"hash":    func(length int, alphabet string) (CodeGenerator, error) { return NewHashGenerator(length, alphabet) },
+	} {
+		factory := factory
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range []struct {
+				length   int
+				alphabet string
+			}{
+				{0, Base62Alphabet},
+				{maxCodeLength + 1, Base62Alphabet},
+				{7, "a"},
+				{7, "abca"},
+				{7, "ab/c"},
+				{7, "ab.c"},
+			} {
+				_, err := factory(tc.length, tc.alphabet)
+				require.ErrorIs(t, err, ErrInvalidGenerator, "%d %q", tc.length, tc.alphabet)
+			}
+
+			for _, tc := range []struct {
+				length   int
+				alphabet string
+			}{
+				{DefaultCodeLength, Base62Alphabet},
+				{4, "01"},
+				{maxCodeLength, "abc-_"},
+			} {
+				generator, err := factory(tc.length, tc.alphabet)
+				require.NoError(t, err)
+				seen := map[string]bool{}
+				for i := 0; i < 100; i++ {
+					code, err := generator.Generate(fmt.Sprintf("https://%d.ru", i), 0)
+					require.NoError(t, err)
+					if name == "counter" {
+						require.GreaterOrEqual(t, len(code), tc.length)
+					} else {
+						require.Len(t, code, tc.length)
+					}
+					require.Empty(t, strings.Trim(code, tc.alphabet), code)
+					seen[code] = true
+				}
+				if tc.length > 4 {
+					require.Len(t, seen, 100)
+				}
+			}
+		})
+	}
+}
+
+func TestCounterGenerator(t *testing.T) {
+	generator, err := NewCounterGenerator(2, "01")
+	require.NoError(t, err)
+	var codes []string
+	for i := 0; i < 6; i++ {
+		code, err := generator.Generate("", 0)
+		require.NoError(t, err)
+		codes = append(codes, code)
+	}
+	// padded to the length, longer once it runs out
+	require.Equal(t, []string{"00", "01", "10", "11", "100", "101"}, codes)
+
+	generator, err = NewCounterGenerator(3, Base62Alphabet)
+	require.NoError(t, err)
+	generator.Start(62)
+	code, err := generator.Generate("", 0)
+	require.NoError(t, err)
+	require.Equal(t, "010", code)
+}
+
+func TestHashGenerator(t *testing.T) {
+	generator, err := NewHashGenerator(DefaultCodeLength, Base62Alphabet)
+	require.NoError(t, err)
+	first, err := generator.Generate("https://yandex.ru", 0)
+	require.NoError(t, err)
+	again, err := generator.Generate("https://yandex.ru", 0)
+	require.NoError(t, err)
+	probe, err := generator.Generate("https://yandex.ru", 1)
+	require.NoError(t, err)
+	require.Equal(t, first, again)
+	require.NotEqual(t, first, probe)
+}
+
+// a single character of a 2-letter alphabet collides on the third link
+func TestURLShortener_Collisions(t *testing.T) {
+	for name, factory := range map[string]func() (CodeGenerator, error){
+		"random": func() (CodeGenerator, error) { return NewRandomGenerator(1, "ab") },
+		"hash":   func() (CodeGenerator, error) { return NewHashGenerator(1, "ab") },
+	} {
+		factory := factory
+		t.Run(name, func(t *testing.T) {
+			generator, err := factory()
+			require.NoError(t, err)
+			srv := NewShortener("", WithGenerator(generator), WithMaxAttempts(50))
+			r := chi.NewMux()
+			r.Put("/", srv.HandleSave)
+			s := httptest.NewServer(r)
+			defer s.Close()
+			srv.addr = s.URL
+
+			links := map[string]bool{}
+			for i := 0; i < 3; i++ {
+				req, _ := http.NewRequest(http.MethodPut, s.URL+"?u="+url.QueryEscape(fmt.Sprintf("https://%d.ru", i)), nil)
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				link, err := ioutil.ReadAll(resp.Body)
+				require.NoError(t, err)
+				_ = resp.Body.Close()
+				if i < 2 {
+					require.Equal(t, http.StatusCreated, resp.StatusCode)
+					require.Len(t, strings.TrimPrefix(string(link), s.URL+"/"), 1)
+					links[string(link)] = true
+				} else {
+					require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+				}
+			}
+			require.Len(t, links, 2)
+		})
+	}
+}
+
+// after a restart the counter starts from scratch and skips the codes it has already issued
+func TestURLShortener_CounterRestart(t *testing.T) {
+	storage := NewMemoryStorage()
+	require.NoError(t, storage.Save("a", "https://a.ru"))
+	require.NoError(t, storage.Save("b", "https://b.ru"))
+	generator, err := NewCounterGenerator(1, "ab")
+	require.NoError(t, err)
+	srv := NewShortenerWithStorage("", storage, WithGenerator(generator))
+
+	rw := httptest.NewRecorder()
+	srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/?u="+url.QueryEscape("https://c.ru"), nil))
+	require.Equal(t, http.StatusCreated, rw.Code)
+	require.Equal(t, "/ba", rw.Body.String())
+}
+
+// a counter started from Len of a reopened storage issues free codes right away
+func TestURLShortener_CounterStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.db")
+	storage, err := NewBoltStorage(path)
+	require.NoError(t, err)
+	generator, err := NewCounterGenerator(DefaultCodeLength, Base62Alphabet)
+	require.NoError(t, err)
+	srv := NewShortenerWithStorage("", storage, WithGenerator(generator))
+	save := func(target string) int {
+		rw := httptest.NewRecorder()
+		srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/save?u="+url.QueryEscape(target), nil))
+		return rw.Code
+	}
+	for i := 0; i < 25; i++ {
+		require.Equal(t, http.StatusCreated, save(fmt.Sprintf("https://%d.ru", i)))
+	}
+	require.NoError(t, storage.Close())
+
+	storage, err = NewBoltStorage(path)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, storage.Close())
+	}()
+	count, err := storage.Len()
+	require.NoError(t, err)
+	generator, err = NewCounterGenerator(DefaultCodeLength, Base62Alphabet)
+	require.NoError(t, err)
+	generator.Start(uint64(count))
+	srv = NewShortenerWithStorage("", storage, WithGenerator(generator))
+	for i := 25; i < 29; i++ {
+		require.Equal(t, http.StatusCreated, save(fmt.Sprintf("https://%d.ru", i)))
+	}
+}
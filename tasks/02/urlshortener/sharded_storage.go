@@ -0,0 +1,103 @@
+package urlshortener
+
+import (
+	"runtime"
+	"sync"
+)
+
+type storageShard struct {
+	mu      sync.RWMutex
+	mapping map[string]string
+	// url -> the first key saved for it, for urls hashed to this shard
+	keys map[string]string
+}
+
+// ShardedStorage is an in-memory Storage for read-heavy load: keys are spread over
+// shards with their own RW locks, so redirects rarely wait for each other or for saves
+type ShardedStorage struct {
+	shards []storageShard
+	mask   uint32
+}
+
+// NewShardedStorage rounds shards up to a power of two, 0 means a few per CPU
+func NewShardedStorage(shards int) *ShardedStorage {
+	if shards <= 0 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	count := 1
+	for count < shards {
+		count <<= 1
+	}
+	s := &ShardedStorage{shards: make([]storageShard, count), mask: uint32(count - 1)}
+	for i := range s.shards {
+		s.shards[i].mapping = map[string]string{}
+		s.shards[i].keys = map[string]string{}
+	}
+	return s
+}
+
+// FNV-1a, inlined to avoid allocating a hash.Hash per call
+func (s *ShardedStorage) shard(key string) *storageShard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return &s.shards[hash&s.mask]
+}
+
+func (s *ShardedStorage) Save(key string, url string) error {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	if _, ok := shard.mapping[key]; ok {
+		shard.mu.Unlock()
+		return ErrKeyExists
+	}
+	shard.mapping[key] = url
+	shard.mu.Unlock()
+
+	// the url may live in another shard, locks are never nested
+	shard = s.shard(url)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, ok := shard.keys[url]; !ok {
+		shard.keys[url] = key
+	}
+	return nil
+}
+
+func (s *ShardedStorage) Load(key string) (string, error) {
+	shard := s.shard(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	url, ok := shard.mapping[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return url, nil
+}
+
+func (s *ShardedStorage) Find(url string) (string, error) {
+	shard := s.shard(url)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	key, ok := shard.keys[url]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return key, nil
+}
+
+func (s *ShardedStorage) Len() (int, error) {
+	count := 0
+	for i := range s.shards {
+		s.shards[i].mu.RLock()
+		count += len(s.shards[i].mapping)
+		s.shards[i].mu.RUnlock()
+	}
+	return count, nil
+}
+
+func (s *ShardedStorage) Close() error {
+	return nil
+}
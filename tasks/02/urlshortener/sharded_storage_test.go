@@ -0,0 +1,154 @@
+package urlshortener
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedStorageShards(t *testing.T) {
+	for shards, expected := range map[int]int{1: 1, 3: 4, 16: 16, 17: 32} {
+		require.Len(t, NewShardedStorage(shards).shards, expected)
+	}
+	require.NotEmpty(t, NewShardedStorage(0).shards)
+}
+
+// run with -race
+func TestStorageConcurrent(t *testing.T) {
+	const (
+		writers = 8
+		readers = 32
+		keys    = 500
+	)
+	for name, storage := range map[string]Storage{"memory": NewMemoryStorage(), "sharded": NewShardedStorage(16)} {
+		storage := storage
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			// every writer tries every key, exactly one of them wins it
+			wins := make([]int, keys)
+			var winsMu sync.Mutex
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for k := 0; k < keys; k++ {
+						err := storage.Save(strconv.Itoa(k), fmt.Sprintf("https://%d.ru", w))
+						if err == nil {
+							winsMu.Lock()
+							wins[k]++
+							winsMu.Unlock()
+						} else {
+							assert.ErrorIs(t, err, ErrKeyExists)
+						}
+					}
+				}(w)
+			}
+			for r := 0; r < readers; r++ {
+				wg.Add(1)
+				go func(r int) {
+					defer wg.Done()
+					for k := 0; k < keys; k++ {
+						_, err := storage.Load(strconv.Itoa((k + r) % keys))
+						if err != nil {
+							assert.ErrorIs(t, err, ErrNotFound)
+						}
+					}
+				}(r)
+			}
+			wg.Wait()
+			for k := 0; k < keys; k++ {
+				require.Equal(t, 1, wins[k], k)
+				_, err := storage.Load(strconv.Itoa(k))
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// run with -race, used to crash with "concurrent map writes"
+func TestURLShortener_Concurrent(t *testing.T) {
+	srv := NewShortener("")
+	r := chi.NewMux()
+	r.Put("/", srv.HandleSave)
+	r.Get("/{key}", srv.HandleExpand)
+	s := httptest.NewServer(r)
+	defer s.Close()
+	srv.addr = s.URL
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				target := fmt.Sprintf("https://site%d.ru/%d", i, j)
+				req, _ := http.NewRequest(http.MethodPut, s.URL+"?u="+url.QueryEscape(target), nil)
+				resp, err := client.Do(req)
+				if !assert.NoError(t, err) {
+					return
+				}
+				link, err := ioutil.ReadAll(resp.Body)
+				assert.NoError(t, err)
+				_ = resp.Body.Close()
+				assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+				resp, err = client.Get(string(link))
+				if !assert.NoError(t, err) {
+					return
+				}
+				_ = resp.Body.Close()
+				assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+				assert.Equal(t, target, resp.Header.Get("Location"))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// go test -bench Storage -cpu 1,4,16
+func BenchmarkStorage(b *testing.B) {
+	const keys = 10000
+	for name, factory := range map[string]func() Storage{
+		"mutex":   func() Storage { return NewMemoryStorage() },
+		"sharded": func() Storage { return NewShardedStorage(0) },
+	} {
+		for _, writePercent := range []int{1, 10, 50} {
+			factory, writePercent := factory, writePercent
+			b.Run(fmt.Sprintf("%s/writes=%d%%", name, writePercent), func(b *testing.B) {
+				storage := factory()
+				names := make([]string, keys)
+				for i := range names {
+					names[i] = strconv.Itoa(i)
+					_ = storage.Save(names[i], "https://example.com")
+				}
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					i := 0
+					for pb.Next() {
+						i++
+						if i%100 < writePercent {
+							// mostly taken keys, the lock is held as for a new one
+							_ = storage.Save(names[i%keys], "https://example.com")
+						} else {
+							_, _ = storage.Load(names[i%keys])
+						}
+					}
+				})
+			})
+		}
+	}
+}
@@ -3,49 +3,158 @@ package urlshortener
 import (
 	"net/http"
 	"net/url"
-	"crypto/md5"
-	"encoding/hex"
 	"fmt"
+	"errors"
+	"io"
 	"github.com/go-chi/chi"
 )
 
+const defaultMaxAttempts = 10
+
+// longer URLs are rejected with 414, browsers and proxies don't handle them anyway
+const MaxURLLength = 8 << 10
+
+// HandleSave tells whether the link is new (201 Created) or was already saved (200 OK)
+// in this header as well
+const (
+	LinkStatusHeader = "X-Link-Status"
+	LinkCreated = "created"
+	LinkExisting = "existing"
+)
+
 type URLShortener struct {
 	addr string
-	mapping map[string]string
+	storage Storage
+	generator CodeGenerator
+	maxAttempts int
+	reserved map[string]bool
+}
+
+type Option func(*URLShortener)
+
+// WithGenerator sets how codes are made, HashGenerator of DefaultCodeLength base62 characters by default
+func WithGenerator(generator CodeGenerator) Option {
+	return func(s *URLShortener) {
+		s.generator = generator
+	}
+}
+
+// WithMaxAttempts sets how many taken codes are skipped before HandleSave gives up with 500
+func WithMaxAttempts(attempts int) Option {
+	return func(s *URLShortener) {
+		s.maxAttempts = attempts
+	}
 }
 
-func NewShortener(addr string) *URLShortener {
-	return &URLShortener{
+// NewShortener keeps links in memory
+func NewShortener(addr string, opts ...Option) *URLShortener {
+	return NewShortenerWithStorage(addr, NewShardedStorage(0), opts...)
+}
+
+func NewShortenerWithStorage(addr string, storage Storage, opts ...Option) *URLShortener {
+	generator, _ := NewHashGenerator(DefaultCodeLength, Base62Alphabet)
+	s := &URLShortener{
 		addr: addr,
-		mapping: map[string]string{},
+		storage: storage,
+		generator: generator,
+		maxAttempts: defaultMaxAttempts,
+		reserved: map[string]bool{},
+	}
+	for _, word := range defaultReservedAliases {
+		s.reserved[word] = true
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *URLShortener) HandleSave(rw http.ResponseWriter, req *http.Request) {
 	raw_url := req.URL.Query().Get("u")
+	if len(raw_url) > MaxURLLength {
+		rw.WriteHeader(http.StatusRequestURITooLong)
+		return
+	}
 	_, err := url.Parse(raw_url)
 	if err != nil {
 		rw.WriteHeader(http.StatusBadRequest)
+		return
 	}
-	hasher := md5.New()
-	hasher.Write([]byte(raw_url))
-	mapped_path := hex.EncodeToString(hasher.Sum(nil))
-	if _, ok := s.mapping[mapped_path]; ok {
+	var mapped_path string
+	var created bool
+	if alias := req.URL.Query().Get("alias"); alias != "" {
+		if err := s.checkAlias(alias); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mapped_path = alias
+		created, err = s.saveCode(alias, raw_url)
+		if errors.Is(err, ErrKeyExists) {
+			rw.WriteHeader(http.StatusConflict)
+			return
+		}
+	} else {
+		// a retried save gets the link it has already got
+		mapped_path, err = s.storage.Find(raw_url)
+		if errors.Is(err, ErrNotFound) {
+			mapped_path, created, err = s.saveWithNewCode(raw_url)
+		}
+	}
+	if err != nil {
 		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	link := fmt.Sprintf("%s/%s", s.addr, mapped_path)
+	if created {
+		rw.Header().Set("Location", link)
+		rw.Header().Set(LinkStatusHeader, LinkCreated)
+		rw.WriteHeader(http.StatusCreated)
 	} else {
-		s.mapping[mapped_path] = raw_url
-		_, err := fmt.Fprintf(rw, "%s/%s", s.addr, mapped_path)
+		rw.Header().Set(LinkStatusHeader, LinkExisting)
+		rw.WriteHeader(http.StatusOK)
+	}
+	_, _ = io.WriteString(rw, link)
+}
+
+// tries codes until one is free, ErrKeyExists if all attempts are taken
+func (s *URLShortener) saveWithNewCode(raw_url string) (string, bool, error) {
+	for attempt := 0; attempt < s.maxAttempts; attempt++ {
+		code, err := s.generator.Generate(raw_url, attempt)
 		if err != nil {
-			rw.WriteHeader(http.StatusInternalServerError)
+			return "", false, err
+		}
+		created, err := s.saveCode(code, raw_url)
+		if !errors.Is(err, ErrKeyExists) {
+			return code, created, err
 		}
 	}
+	return "", false, ErrKeyExists
+}
+
+// saves url under code; a code taken by the same url (saved concurrently with a deterministic
+// generator, or a repeated alias) is not created again, taken by another one is ErrKeyExists
+func (s *URLShortener) saveCode(code string, raw_url string) (bool, error) {
+	err := s.storage.Save(code, raw_url)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, ErrKeyExists) {
+		return false, err
+	}
+	if saved, err := s.storage.Load(code); err == nil && saved == raw_url {
+		return false, nil
+	}
+	return false, ErrKeyExists
 }
 
 func (s *URLShortener) HandleExpand(rw http.ResponseWriter, req *http.Request) {
 	r_url := chi.URLParam(req, "key")
-	if mapped_path, ok := s.mapping[r_url]; ok {
+	mapped_path, err := s.storage.Load(r_url)
+	if err == nil {
 		http.Redirect(rw, req, mapped_path, http.StatusMovedPermanently)
-	} else {
+	} else if errors.Is(err, ErrNotFound) {
 		rw.WriteHeader(http.StatusNotFound)
+	} else {
+		rw.WriteHeader(http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,80 @@
+package urlshortener
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrNotFound  = errors.New("key not found")
+	ErrKeyExists = errors.New("key already exists")
+)
+
+// Storage keeps short keys and their URLs. Implementations are safe for concurrent use.
+type Storage interface {
+	// Save stores url under key unless the key is taken (ErrKeyExists)
+	Save(key string, url string) error
+	// Load returns the URL saved under key or ErrNotFound
+	Load(key string) (string, error)
+	// Find returns the first key saved for url or ErrNotFound
+	Find(url string) (string, error)
+	// Len returns the number of saved links
+	Len() (int, error)
+	Close() error
+}
+
+// MemoryStorage keeps links in memory behind one lock, they are lost on restart.
+// ShardedStorage scales better under concurrent load.
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	mapping map[string]string
+	// url -> the first key saved for it
+	keys map[string]string
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{mapping: map[string]string{}, keys: map[string]string{}}
+}
+
+func (s *MemoryStorage) Save(key string, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.mapping[key]; ok {
+		return ErrKeyExists
+	}
+	s.mapping[key] = url
+	if _, ok := s.keys[url]; !ok {
+		s.keys[url] = key
+	}
+	return nil
+}
+
+func (s *MemoryStorage) Load(key string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	url, ok := s.mapping[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return url, nil
+}
+
+func (s *MemoryStorage) Find(url string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[url]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return key, nil
+}
+
+func (s *MemoryStorage) Len() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.mapping), nil
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
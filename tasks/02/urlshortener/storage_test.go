@@ -0,0 +1,232 @@
+package urlshortener
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+var storageFactories = map[string]func(t *testing.T, path string) Storage{
+	"memory": func(t *testing.T, path string) Storage {
+		return NewMemoryStorage()
+	},
+	"sharded": func(t *testing.T, path string) Storage {
+		return NewShardedStorage(8)
+	},
+	"bolt": func(t *testing.T, path string) Storage {
+		storage, err := NewBoltStorage(path)
+		require.NoError(t, err)
+		return storage
+	},
+	"file": func(t *testing.T, path string) Storage {
+		storage, err := NewFileStorage(path)
+		require.NoError(t, err)
+		return storage
+	},
+}
+
+func TestStorage(t *testing.T) {
+	for name, factory := range storageFactories {
+		factory := factory
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "links")
+			storage := factory(t, path)
+
+			_, err := storage.Load("yandex")
+			require.ErrorIs(t, err, ErrNotFound)
+			require.NoError(t, storage.Save("yandex", "https://yandex.ru"))
+			require.NoError(t, storage.Save("google", "https://google.com"))
+			require.ErrorIs(t, storage.Save("yandex", "https://evil.com"), ErrKeyExists)
+			require.NoError(t, storage.Save("ya", "https://yandex.ru"))
+
+			url, err := storage.Load("yandex")
+			require.NoError(t, err)
+			require.Equal(t, "https://yandex.ru", url)
+			// the first key of a URL is found, a rejected save is not indexed
+			key, err := storage.Find("https://yandex.ru")
+			require.NoError(t, err)
+			require.Equal(t, "yandex", key)
+			_, err = storage.Find("https://evil.com")
+			require.ErrorIs(t, err, ErrNotFound)
+			count, err := storage.Len()
+			require.NoError(t, err)
+			require.Equal(t, 3, count)
+			require.NoError(t, storage.Close())
+
+			if name == "memory" || name == "sharded" {
+				return
+			}
+			// persistent storages survive a restart
+			storage = factory(t, path)
+			defer func() {
+				require.NoError(t, storage.Close())
+			}()
+			for key, expected := range map[string]string{"yandex": "https://yandex.ru", "google": "https://google.com"} {
+				url, err := storage.Load(key)
+				require.NoError(t, err)
+				require.Equal(t, expected, url)
+				found, err := storage.Find(expected)
+				require.NoError(t, err)
+				require.Equal(t, key, found)
+			}
+			require.ErrorIs(t, storage.Save("google", "https://evil.com"), ErrKeyExists)
+			count, err = storage.Len()
+			require.NoError(t, err)
+			require.Equal(t, 3, count)
+		})
+	}
+}
+
+func TestFileStorageCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.jsonl")
+	// a duplicate key (the first one wins) and a torn line after a crash
+	require.NoError(t, os.WriteFile(path, []byte(
+		`{"k":"a","u":"https://a.ru"}`+"\n"+
+			`{"k":"b","u":"https://b.ru"}`+"\n"+
+			`{"k":"a","u":"https://evil.com"}`+"\n"+
+			`{"k":"c","u":"https://c`), 0o600))
+
+	storage, err := NewFileStorage(path)
+	require.NoError(t, err)
+	url, err := storage.Load("a")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru", url)
+	_, err = storage.Load("c")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(content), "\n"))
+	require.NotContains(t, string(content), "evil")
+
+	// appends after compaction go to the new file
+	require.NoError(t, storage.Save("c", "https://c.ru"))
+	require.NoError(t, storage.Compact())
+	require.NoError(t, storage.Save("d", "https://d.ru"))
+	require.NoError(t, storage.Close())
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 4, strings.Count(string(content), "\n"))
+}
+
+// compaction keeps the key Find returns for a URL, otherwise a retried save gets another link
+func TestFileStorageCompactionKeepsFind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.jsonl")
+	storage, err := NewFileStorage(path)
+	require.NoError(t, err)
+	for i := 0; i < 8; i++ {
+		require.NoError(t, storage.Save(fmt.Sprintf("key%d", i), "https://yandex.ru"))
+	}
+	require.NoError(t, storage.Compact())
+	require.NoError(t, storage.Close())
+
+	storage, err = NewFileStorage(path)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, storage.Close())
+	}()
+	key, err := storage.Find("https://yandex.ru")
+	require.NoError(t, err)
+	require.Equal(t, "key0", key)
+	count, err := storage.Len()
+	require.NoError(t, err)
+	require.Equal(t, 8, count)
+}
+
+// used to fail to reopen with "bufio.Scanner: token too long"
+func TestFileStorageLongURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.jsonl")
+	long := "https://example.com/?q=" + strings.Repeat("x", 70<<10)
+	storage, err := NewFileStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, storage.Save("long", long))
+	require.NoError(t, storage.Save("short", "https://a.ru"))
+	require.NoError(t, storage.Close())
+
+	storage, err = NewFileStorage(path)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, storage.Close())
+	}()
+	for key, expected := range map[string]string{"long": long, "short": "https://a.ru"} {
+		url, err := storage.Load(key)
+		require.NoError(t, err)
+		require.Equal(t, expected, url)
+	}
+}
+
+// a link which failed to reach the log is not served, it would be lost on restart
+func TestFileStorageWriteFailure(t *testing.T) {
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "links.jsonl"))
+	require.NoError(t, err)
+	require.NoError(t, storage.file.Close())
+	require.Error(t, storage.Save("a", "https://a.ru"))
+	_, err = storage.Load("a")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestURLShortener_Restart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.db")
+	storage, err := NewBoltStorage(path)
+	require.NoError(t, err)
+	srv := NewShortenerWithStorage("", storage)
+
+	r := chi.NewMux()
+	r.Put("/", srv.HandleSave)
+	s := httptest.NewServer(r)
+	defer s.Close()
+	req, _ := http.NewRequest(http.MethodPut, s.URL+"?u="+url.QueryEscape("https://yandex.ru"), nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	link, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.NoError(t, storage.Close())
+
+	storage, err = NewBoltStorage(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = storage.Close()
+	}()
+	srv = NewShortenerWithStorage("", storage)
+	r = chi.NewMux()
+	r.Get("/{key}", srv.HandleExpand)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, string(link), nil))
+	require.Equal(t, http.StatusMovedPermanently, rec.Code)
+	require.Equal(t, "https://yandex.ru", rec.Header().Get("Location"))
+}
+
+// databases of older versions have no urls bucket, it is built on open
+func TestBoltStorageIndexMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.db")
+	db, err := bolt.Open(path, 0o600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		links, err := tx.CreateBucket(linksBucket)
+		if err != nil {
+			return err
+		}
+		return links.Put([]byte("yandex"), []byte("https://yandex.ru"))
+	}))
+	require.NoError(t, db.Close())
+
+	storage, err := NewBoltStorage(path)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, storage.Close())
+	}()
+	key, err := storage.Find("https://yandex.ru")
+	require.NoError(t, err)
+	require.Equal(t, "yandex", key)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi"
@@ -32,14 +33,14 @@ func TestURLShortener(t *testing.T) {
 
 	resp, err := client.Do(reqY)
 	require.NoError(t, err)
-	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
 	yLink, err := ioutil.ReadAll(resp.Body)
 	require.NoError(t, err)
 	_ = resp.Body.Close()
 
 	resp, err = client.Do(reqG)
 	require.NoError(t, err)
-	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
 	gLink, err := ioutil.ReadAll(resp.Body)
 	require.NoError(t, err)
 	_ = resp.Body.Close()
@@ -110,4 +111,59 @@ func TestURLShortener_NotFound(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusNotFound, resp.StatusCode)
 	_ = resp.Body.Close()
-}
\ No newline at end of file
+}
+
+func TestURLShortener_Idempotent(t *testing.T) {
+	counter, err := NewCounterGenerator(DefaultCodeLength, Base62Alphabet)
+	require.NoError(t, err)
+	random, err := NewRandomGenerator(DefaultCodeLength, Base62Alphabet)
+	require.NoError(t, err)
+	hash, err := NewHashGenerator(DefaultCodeLength, Base62Alphabet)
+	require.NoError(t, err)
+
+	for _, generator := range []CodeGenerator{counter, random, hash} {
+		srv := NewShortener("", WithGenerator(generator))
+
+		rw := httptest.NewRecorder()
+		srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/save?u="+url.QueryEscape("https://yandex.ru"), nil))
+		require.Equal(t, http.StatusCreated, rw.Code)
+		require.Equal(t, LinkCreated, rw.Header().Get(LinkStatusHeader))
+		require.Equal(t, rw.Body.String(), rw.Header().Get("Location"))
+		yLink := rw.Body.String()
+
+		rw = httptest.NewRecorder()
+		srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/save?u="+url.QueryEscape("https://yandex.ru"), nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, LinkExisting, rw.Header().Get(LinkStatusHeader))
+		require.Equal(t, yLink, rw.Body.String())
+
+		rw = httptest.NewRecorder()
+		srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/save?u="+url.QueryEscape("https://google.com"), nil))
+		require.Equal(t, http.StatusCreated, rw.Code)
+		require.NotEqual(t, yLink, rw.Body.String())
+	}
+}
+
+// the hash code of a URL saved concurrently is taken by the same URL, not created twice
+func TestURLShortener_IdempotentRace(t *testing.T) {
+	storage := NewMemoryStorage()
+	srv := NewShortenerWithStorage("", storage)
+	code, err := srv.generator.Generate("https://yandex.ru", 0)
+	require.NoError(t, err)
+	require.NoError(t, storage.Save(code, "https://yandex.ru"))
+	// as if Find ran before the concurrent Save
+	delete(storage.keys, "https://yandex.ru")
+
+	rw := httptest.NewRecorder()
+	srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/save?u="+url.QueryEscape("https://yandex.ru"), nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.Equal(t, "/"+code, rw.Body.String())
+}
+
+func TestURLShortener_TooLong(t *testing.T) {
+	srv := NewShortener("")
+	rw := httptest.NewRecorder()
+	target := "https://example.com/?q=" + strings.Repeat("x", MaxURLLength)
+	srv.HandleSave(rw, httptest.NewRequest(http.MethodPut, "/save?u="+url.QueryEscape(target), nil))
+	require.Equal(t, http.StatusRequestURITooLong, rw.Code)
+}
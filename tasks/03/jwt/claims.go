@@ -0,0 +1,208 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// registered claims of RFC 7519, section 4.1
+type registeredClaims struct {
+	ExpTime   numericDate `json:"exp,omitempty"`
+	Issuer    string      `json:"iss,omitempty"`
+	Subject   string      `json:"sub,omitempty"`
+	Audience  audience    `json:"aud,omitempty"`
+	NotBefore numericDate `json:"nbf,omitempty"`
+	IssuedAt  numericDate `json:"iat,omitempty"`
+	ID        string      `json:"jti,omitempty"`
+}
+
+// a NumericDate of RFC 7519, section 2: seconds since the epoch, which may be fractional.
+// Encode writes whole seconds, fractions of decoded values are truncated.
+type numericDate int64
+
+func (d *numericDate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	// json.Number would also take a quoted number
+	if len(data) > 0 && data[0] == '"' {
+		return fmt.Errorf("numeric date %s is not a number", data)
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	if seconds, err := number.Int64(); err == nil {
+		*d = numericDate(seconds)
+		return nil
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return err
+	}
+	if seconds < math.MinInt64 || seconds >= math.MaxInt64 {
+		return fmt.Errorf("numeric date %s is out of range", number)
+	}
+	*d = numericDate(seconds)
+	return nil
+}
+
+// zero time for absent claims
+func (d numericDate) time() time.Time {
+	if d == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(d), 0)
+}
+
+// "aud" is either a single string or an array of strings
+type audience []string
+
+func (a audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+func (a audience) contains(values []string) bool {
+	for _, value := range values {
+		for _, aud := range a {
+			if aud == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func registeredClaimsAssembly(configuration *config) registeredClaims {
+	claims := registeredClaims{
+		Issuer:   configuration.Issuer,
+		Subject:  configuration.Subject,
+		Audience: configuration.Audience,
+		ID:       configuration.ID,
+	}
+	// else exp will be 0 by default
+	if configuration.Expires != nil {
+		claims.ExpTime = numericDate(configuration.Expires.Unix())
+	}
+	if configuration.TTL != nil {
+		claims.ExpTime = numericDate(configuration.now().Add(*configuration.TTL).Unix())
+	}
+	if configuration.NotBefore != nil {
+		claims.NotBefore = numericDate(configuration.NotBefore.Unix())
+	}
+	if configuration.IssuedAt != nil {
+		claims.IssuedAt = numericDate(configuration.IssuedAt.Unix())
+	}
+	return claims
+}
+
+// checks everything except exp, which is checked by jwtIsAlreadyExpired
+func jwtValidateClaims(configuration *config, claims *registeredClaims, v *validation) {
+	// leeway moves the current time forward for nbf and iat
+	now := configuration.now().Add(configuration.Leeway)
+	if v.proceed() && claims.NotBefore != 0 && now.Before(claims.NotBefore.time()) {
+		v.add("nbf", ErrTokenNotValidYet, nil)
+	}
+	if v.proceed() && claims.IssuedAt != 0 && now.Before(claims.IssuedAt.time()) {
+		v.add("iat", ErrTokenUsedBeforeIssued, nil)
+	}
+	if v.proceed() && configuration.Issuer != "" && claims.Issuer != configuration.Issuer {
+		v.add("iss", ErrInvalidIssuer, nil)
+	}
+	if v.proceed() && configuration.RequireSubject && claims.Subject == "" {
+		v.add("sub", ErrSubjectMissing, nil)
+	}
+	if v.proceed() && configuration.Subject != "" && claims.Subject != configuration.Subject {
+		v.add("sub", ErrInvalidSubject, nil)
+	}
+	if v.proceed() && len(configuration.Audience) != 0 && !claims.Audience.contains(configuration.Audience) {
+		v.add("aud", ErrInvalidAudience, nil)
+	}
+}
+
+// user data as the top-level claim set; registered claims from options take precedence
+// over the same fields of data
+func flatClaimsAssembly(configuration *config, data interface{}) (map[string]json.RawMessage, error) {
+	claims := map[string]json.RawMessage{}
+	if data != nil {
+		js, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		// null is also rejected here: the claim set must be a JSON object
+		if err = json.Unmarshal(js, &claims); err != nil || claims == nil {
+			return nil, ErrInvalidPayload
+		}
+	}
+	js, err := json.Marshal(registeredClaimsAssembly(configuration))
+	if err != nil {
+		return nil, err
+	}
+	var registered map[string]json.RawMessage
+	if err = json.Unmarshal(js, &registered); err != nil {
+		return nil, err
+	}
+	for name, value := range registered {
+		claims[name] = value
+	}
+	return claims, nil
+}
+
+// RegisteredClaims are the registered claims of a verified token, zero values mean absent claims
+type RegisteredClaims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+}
+
+// Claims are registered claims and raw user data ("d", or the whole claim set for WithFlatClaims)
+// of a verified token
+type Claims struct {
+	RegisteredClaims
+	Data json.RawMessage
+}
+
+// DecodeClaims is Decode which also returns registered claims; user data is left raw
+func DecodeClaims(token []byte, opts ...Option) (*Claims, error) {
+	parsed, err := Parse(token, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Verify(opts...)
+}
+
+// a copy: the audience slice of the result can be changed without touching the token
+func (c *registeredClaims) export() RegisteredClaims {
+	return RegisteredClaims{
+		Issuer:    c.Issuer,
+		Subject:   c.Subject,
+		Audience:  append([]string(nil), c.Audience...),
+		ExpiresAt: c.ExpTime.time(),
+		NotBefore: c.NotBefore.time(),
+		IssuedAt:  c.IssuedAt.time(),
+		ID:        c.ID,
+	}
+}
@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var ClaimsTestCases = []struct {
+	EncodeOpts []Option
+	DecodeOpts []Option
+	Payload    string
+	Err        error
+}{
+	{
+		EncodeOpts: []Option{WithIssuer("auth"), WithSubject("user-1"), WithAudience("api"), WithID("42"),
+			WithNotBefore(time.Unix(5, 0)), WithIssuedAt(time.Unix(5, 0)), WithExpires(time.Unix(100, 0))},
+		DecodeOpts: []Option{WithIssuer("auth"), WithSubject("user-1"), RequireSubject(), WithAudience("other", "api")},
+		Payload:    `{"d":"data","exp":100,"iss":"auth","sub":"user-1","aud":"api","nbf":5,"iat":5,"jti":"42"}`,
+	},
+	{
+		EncodeOpts: []Option{WithAudience("api", "web")},
+		DecodeOpts: []Option{WithAudience("web")},
+		Payload:    `{"d":"data","aud":["api","web"]}`,
+	},
+	{
+		EncodeOpts: []Option{WithNotBefore(time.Unix(20, 0))},
+		Err:        ErrTokenNotValidYet,
+	},
+	{
+		EncodeOpts: []Option{WithIssuedAt(time.Unix(20, 0))},
+		Err:        ErrTokenUsedBeforeIssued,
+	},
+	{
+		EncodeOpts: []Option{WithIssuer("evil")},
+		DecodeOpts: []Option{WithIssuer("auth")},
+		Err:        ErrInvalidIssuer,
+	},
+	{
+		DecodeOpts: []Option{WithIssuer("auth")},
+		Err:        ErrInvalidIssuer,
+	},
+	{
+		DecodeOpts: []Option{RequireSubject()},
+		Err:        ErrSubjectMissing,
+	},
+	{
+		EncodeOpts: []Option{WithSubject("user-2")},
+		DecodeOpts: []Option{WithSubject("user-1")},
+		Err:        ErrInvalidSubject,
+	},
+	{
+		EncodeOpts: []Option{WithAudience("web")},
+		DecodeOpts: []Option{WithAudience("api")},
+		Err:        ErrInvalidAudience,
+	},
+	{
+		DecodeOpts: []Option{WithAudience("api")},
+		Err:        ErrInvalidAudience,
+	},
+}
+
+func TestRegisteredClaims(t *testing.T) {
+	for i, tc := range ClaimsTestCases {
+		tc := tc
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			signOpts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key")), WithClock(func() time.Time {
+				return time.Unix(10, 0)
+			})}
+
+			token, err := Encode("data", append(signOpts, tc.EncodeOpts...)...)
+			require.NoError(t, err)
+			if tc.Payload != "" {
+				encoded := bytes.Split(token, []byte("."))[1]
+				require.JSONEq(t, tc.Payload, string(b64Bytes(string(encoded))))
+			}
+
+			var data string
+			err = Decode(token, &data, append(signOpts, tc.DecodeOpts...)...)
+			if tc.Err != nil {
+				require.ErrorIs(t, err, tc.Err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "data", data)
+			}
+		})
+	}
+}
+
+func TestFlatClaims(t *testing.T) {
+	type userClaims struct {
+		Name    string `json:"name"`
+		Admin   bool   `json:"admin"`
+		Subject string `json:"sub"`
+		Expires int64  `json:"exp"`
+	}
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key")), WithFlatClaims(), WithClock(func() time.Time {
+		return time.Unix(10, 0)
+	})}
+
+	token, err := Encode(userClaims{Name: "Dmitrii", Admin: true, Subject: "ignored", Expires: 1},
+		append(opts, WithSubject("user-1"), WithTTL(90*time.Second))...)
+	require.NoError(t, err)
+	encoded := bytes.Split(token, []byte("."))[1]
+	require.JSONEq(t, `{"name":"Dmitrii","admin":true,"sub":"user-1","exp":100}`, string(b64Bytes(string(encoded))))
+
+	var claims userClaims
+	require.NoError(t, Decode(token, &claims, append(opts, RequireSubject())...))
+	require.Equal(t, userClaims{Name: "Dmitrii", Admin: true, Subject: "user-1", Expires: 100}, claims)
+
+	require.ErrorIs(t, Decode(token, &claims, append(opts, WithSubject("user-2"))...), ErrInvalidSubject)
+
+	// tokens in the wrapped format have no user data at the top level
+	wrapped, err := Encode(map[string]interface{}{"name": "Ivan"}, WithSignMethod(HS256), WithKey([]byte("secret-key")))
+	require.NoError(t, err)
+	var flat map[string]interface{}
+	require.NoError(t, Decode(wrapped, &flat, opts...))
+	require.Equal(t, map[string]interface{}{"d": map[string]interface{}{"name": "Ivan"}}, flat)
+	var wrappedData map[string]interface{}
+	require.NoError(t, Decode(wrapped, &wrappedData, WithSignMethod(HS256), WithKey([]byte("secret-key"))))
+	require.Equal(t, map[string]interface{}{"name": "Ivan"}, wrappedData)
+
+	_, err = Encode("not an object", opts...)
+	require.ErrorIs(t, err, ErrInvalidPayload)
+
+	token, err = Encode(nil, append(opts, WithIssuer("auth"))...)
+	require.NoError(t, err)
+	var issuerOnly map[string]interface{}
+	require.NoError(t, Decode(token, &issuerOnly, opts...))
+	require.Equal(t, map[string]interface{}{"iss": "auth"}, issuerOnly)
+}
+
+func TestLeeway(t *testing.T) {
+	t.Parallel()
+	at := func(seconds int64) Option {
+		return WithClock(func() time.Time {
+			return time.Unix(seconds, 0)
+		})
+	}
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key"))}
+	token, err := Encode("data", append(opts, at(100), WithNotBefore(time.Unix(100, 0)),
+		WithIssuedAt(time.Unix(100, 0)), WithExpires(time.Unix(200, 0)))...)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Now    int64
+		Leeway time.Duration
+		Err    error
+	}{
+		{Now: 150},
+		{Now: 205, Err: ErrTokenExpired},
+		{Now: 205, Leeway: 10 * time.Second},
+		{Now: 215, Leeway: 10 * time.Second, Err: ErrTokenExpired},
+		{Now: 95, Err: ErrTokenNotValidYet},
+		{Now: 95, Leeway: 10 * time.Second},
+		{Now: 85, Leeway: 10 * time.Second, Err: ErrTokenNotValidYet},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Parallel()
+			var data string
+			err := Decode(token, &data, append(opts[:len(opts):len(opts)], at(tc.Now), WithLeeway(tc.Leeway))...)
+			if tc.Err != nil {
+				require.ErrorIs(t, err, tc.Err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package jwt
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+)
+
+// the only extension understood, see jwtCheckCritical
+const critBase64 = "b64"
+
+// SignDetached signs payload as JWS with detached content (RFC 7515, appendix F): the token
+// is "header..signature" and the payload travels separately, e.g. as a webhook body.
+// With WithUnencodedPayload the payload is signed as is rather than its base64 (RFC 7797).
+// Header options work as for Encode, but there is no "typ" unless WithType is set.
+func SignDetached(payload []byte, opts ...Option) ([]byte, error) {
+	jwtConfiguration := assemblyJWTConfig(opts)
+	signer, err := jwtSigner(jwtConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	h, err := headerAssembly(jwtConfiguration, signer)
+	if err != nil {
+		return nil, err
+	}
+	h.Type = jwtConfiguration.Type
+	if jwtConfiguration.UnencodedPayload {
+		encoded := false
+		h.Base64 = &encoded
+		h.Critical = []string{critBase64}
+	}
+	jwtHeader, err := b64JsonEncode(h)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.Sign(detachedSigningInput([]byte(jwtHeader), payload, jwtConfiguration.UnencodedPayload))
+	if err != nil {
+		return nil, err
+	}
+	var jwt bytes.Buffer
+	jwt.WriteString(jwtHeader)
+	jwt.WriteString("..")
+	jwt.WriteString(b64.RawURLEncoding.EncodeToString(signature))
+	return jwt.Bytes(), nil
+}
+
+// VerifyDetached checks the token created by SignDetached against the payload. Whether the
+// payload is encoded is taken from the "b64" header. Tokens without "typ" are accepted
+// unless WithAllowedTypes is set.
+func VerifyDetached(token []byte, payload []byte, opts ...Option) error {
+	jwtConfiguration := assemblyJWTConfig(opts)
+	err := jwtCheckTokenSize(jwtConfiguration, token)
+	if err != nil {
+		return err
+	}
+	splittedParts := bytes.Split(token, []byte("."))
+	if len(splittedParts) != 3 || len(splittedParts[1]) != 0 {
+		return &ValidationError{Part: "token", Err: ErrInvalidToken}
+	}
+
+	jwt := &jwtParts{Detached: true}
+	err = jwtHeaderDecode(jwtConfiguration, jwt, splittedParts[0])
+	if err != nil {
+		return err
+	}
+	err = jwtSignatureDecode(jwt, splittedParts[2])
+	if err != nil {
+		return err
+	}
+	unencoded := jwt.Header.Base64 != nil && !*jwt.Header.Base64
+	jwt.SigningInput = detachedSigningInput(splittedParts[0], payload, unencoded)
+
+	if jwtConfiguration.AllowedTypes == nil {
+		jwtConfiguration.AllowedTypes = []string{""}
+	}
+	return jwtVerifyParts(jwtConfiguration, jwt)
+}
+
+func detachedSigningInput(jwtHeader []byte, payload []byte, unencoded bool) []byte {
+	var input bytes.Buffer
+	input.Write(jwtHeader)
+	input.WriteString(".")
+	if unencoded {
+		input.Write(payload)
+	} else {
+		input.WriteString(b64.RawURLEncoding.EncodeToString(payload))
+	}
+	return input.Bytes()
+}
+
+// RFC 7515, section 4.1.11: every extension listed in "crit" must be understood, otherwise
+// the token is rejected. The only one is "b64" of detached tokens, and RFC 7797 requires
+// it to be listed.
+func jwtCheckCritical(jwt *jwtParts) error {
+	if jwt.Header.Critical != nil && len(jwt.Header.Critical) == 0 {
+		return &ValidationError{Part: "crit", Err: ErrInvalidToken}
+	}
+	listed := false
+	for _, name := range jwt.Header.Critical {
+		if name != critBase64 || !jwt.Detached || jwt.Header.Base64 == nil {
+			return &ValidationError{Part: "crit", Err: ErrInvalidToken}
+		}
+		listed = true
+	}
+	if jwt.Header.Base64 != nil && !listed {
+		return &ValidationError{Part: "b64", Err: ErrInvalidToken}
+	}
+	return nil
+}
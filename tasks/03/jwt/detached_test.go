@@ -0,0 +1,83 @@
+package jwt
+
+import (
+	b64 "encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the HMAC key of RFC 7515, appendix A.1, also used by RFC 7797
+var rfc7515HMACKey = b64Bytes("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+
+func TestDetachedRFCVectors(t *testing.T) {
+	opts := []Option{WithSignMethod(HS256), WithKey(rfc7515HMACKey)}
+
+	// RFC 7797, section 4.2
+	token, err := SignDetached([]byte("$.02"), append(opts, WithUnencodedPayload())...)
+	require.NoError(t, err)
+	require.Equal(t, "eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY", string(token))
+	require.NoError(t, VerifyDetached(token, []byte("$.02"), opts...))
+	require.ErrorIs(t, VerifyDetached(token, []byte("$.03"), opts...), ErrSignatureInvalid)
+
+	// RFC 7515, appendix A.1 with the payload detached as in appendix F
+	payload, err := b64.RawURLEncoding.DecodeString("eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ")
+	require.NoError(t, err)
+	detached := []byte("eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9..dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	require.NoError(t, VerifyDetached(detached, payload, append(opts, WithAllowedTypes("JWT"))...))
+	// no "typ" is expected by default
+	require.ErrorIs(t, VerifyDetached(detached, payload, opts...), ErrInvalidToken)
+}
+
+func TestDetached(t *testing.T) {
+	opts := []Option{WithSignMethod(ES256), WithPrivateKey(mustPrivateKey("testdata/ec_p256.pem"))}
+	body := []byte(`{"event":"payment.succeeded","amount":42}` + strings.Repeat(" ", 1024))
+
+	for _, encoding := range [][]Option{nil, {WithUnencodedPayload()}} {
+		token, err := SignDetached(body, append(opts, encoding...)...)
+		require.NoError(t, err)
+		parts := strings.Split(string(token), ".")
+		require.Len(t, parts, 3)
+		require.Empty(t, parts[1])
+		require.Less(t, len(token), 200)
+
+		require.NoError(t, VerifyDetached(token, body, opts...))
+		require.ErrorIs(t, VerifyDetached(token, body[1:], opts...), ErrSignatureInvalid)
+		require.ErrorIs(t, VerifyDetached(token, body, WithSignMethod(HS256), WithKey([]byte("key"))), ErrSignMethodMismatched)
+	}
+
+	// a regular token is not a detached one and vice versa
+	regular, err := Encode("data", opts...)
+	require.NoError(t, err)
+	require.ErrorIs(t, VerifyDetached(regular, []byte("data"), opts...), ErrInvalidToken)
+	token, err := SignDetached(body, append(opts, WithUnencodedPayload())...)
+	require.NoError(t, err)
+	var data string
+	require.ErrorIs(t, Decode(token, &data, opts...), ErrInvalidToken)
+}
+
+func TestCriticalHeader(t *testing.T) {
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key"))}
+	testCases := []struct {
+		Header string
+		Part   string
+	}{
+		{Header: `{"alg":"HS256","typ":"JWT","crit":["exp"],"exp":1}`, Part: "crit"},
+		{Header: `{"alg":"HS256","typ":"JWT","crit":[]}`, Part: "crit"},
+		// "b64" is understood only for detached payloads
+		{Header: `{"alg":"HS256","typ":"JWT","b64":false,"crit":["b64"]}`, Part: "crit"},
+		{Header: `{"alg":"HS256","typ":"JWT","b64":true}`, Part: "b64"},
+	}
+	for _, tc := range testCases {
+		token := signRaw(t, tc.Header, `{"d":"data"}`)
+		var data string
+		err := Decode(token, &data, opts...)
+		require.ErrorIs(t, err, ErrInvalidToken, tc.Header)
+		require.Equal(t, tc.Part, err.(*ValidationError).Part, tc.Header)
+	}
+
+	// "b64" must be listed in "crit"
+	detached := []byte(b64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","b64":false}`)) + "..")
+	require.ErrorIs(t, VerifyDetached(detached, []byte("data"), opts...), ErrInvalidToken)
+}
@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// unsecured JWT (RFC 7519, section 6) is never accepted
+const algNone SignMethod = "none"
+
+var errTrailingData = errors.New("data after JSON value")
+
+func jwtCheckTokenSize(configuration *config, token []byte) error {
+	if configuration.MaxTokenSize > 0 && len(token) > configuration.MaxTokenSize {
+		return &ValidationError{
+			Part:  "token",
+			Err:   ErrInvalidToken,
+			Cause: fmt.Errorf("token is %d bytes, limit is %d", len(token), configuration.MaxTokenSize),
+		}
+	}
+	return nil
+}
+
+func jwtCheckAlgorithm(configuration *config, jwt *jwtParts) error {
+	if jwt.Header.Algorithm == algNone {
+		return &ValidationError{Part: "alg", Err: ErrInvalidSignMethod, Cause: errors.New(`"none" is not allowed`)}
+	}
+	if len(configuration.AllowedAlgorithms) == 0 {
+		return nil
+	}
+	for _, method := range configuration.AllowedAlgorithms {
+		if jwt.Header.Algorithm == method {
+			return nil
+		}
+	}
+	return &ValidationError{Part: "alg", Err: ErrInvalidSignMethod}
+}
+
+// base64 of header or payload, non-canonical encoding is rejected in strict mode
+func b64DecodeSegment(configuration *config, data []byte) ([]byte, error) {
+	if !configuration.StrictJSON {
+		return b64DecodeCause(data)
+	}
+	encoding := b64.RawURLEncoding.Strict()
+	decoded := make([]byte, encoding.DecodedLen(len(data)))
+	n, err := encoding.Decode(decoded, data)
+	if err != nil {
+		return nil, err
+	}
+	return decoded[:n], nil
+}
+
+// rejects duplicate keys (case-insensitively) at any depth and anything after the value, including whitespace.
+// The data must be valid JSON already, so the nesting depth is bounded by encoding/json.
+func strictJSONCheck(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	err := strictJSONValue(decoder)
+	if err != nil {
+		return err
+	}
+	if decoder.InputOffset() != int64(len(data)) {
+		return errTrailingData
+	}
+	return nil
+}
+
+func strictJSONValue(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		keys := map[string]bool{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			// encoding/json matches fields case-insensitively, so "exp" and "EXP" are one key;
+			// upper then lower case also folds 'ſ' and the Kelvin sign like it does
+			folded := strings.ToLower(strings.ToUpper(key.(string)))
+			if keys[folded] {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+			keys[folded] = true
+			if err := strictJSONValue(decoder); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	case json.Delim('['):
+		for decoder.More() {
+			if err := strictJSONValue(decoder); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	}
+	return err
+}
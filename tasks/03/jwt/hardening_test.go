@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	b64 "encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signs arbitrary header and payload JSON with HS256
+func signRaw(t testing.TB, header string, payload string) []byte {
+	signer, err := NewSigner(HS256, []byte("secret-key"))
+	require.NoError(t, err)
+	input := b64.RawURLEncoding.EncodeToString([]byte(header)) + "." + b64.RawURLEncoding.EncodeToString([]byte(payload))
+	signature, err := signer.Sign([]byte(input))
+	require.NoError(t, err)
+	return []byte(input + "." + b64.RawURLEncoding.EncodeToString(signature))
+}
+
+func TestHardenedDecode(t *testing.T) {
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key"))}
+	strict := append(opts[:len(opts):len(opts)], WithStrictJSON())
+	valid := signRaw(t, `{"alg":"HS256","typ":"JWT"}`, `{"d":{"name":"Dmitrii"}}`)
+
+	testCases := []struct {
+		Name  string
+		Token []byte
+		Opts  []Option
+		Part  string
+		Err   error
+	}{
+		{Name: "valid", Token: valid, Opts: strict},
+		{Name: "allowed", Token: valid, Opts: append(strict, WithAllowedAlgorithms(HS256, ES256), WithMaxTokenSize(len(valid)))},
+		{Name: "not allowed", Token: valid, Opts: append(opts, WithAllowedAlgorithms(RS256, ES256)), Part: "alg", Err: ErrInvalidSignMethod},
+		{Name: "none", Token: []byte("eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJkIjoxfQ."),
+			Opts: append(opts, WithAllowedAlgorithms(algNone)), Part: "alg", Err: ErrInvalidSignMethod},
+		{Name: "too long", Token: valid, Opts: append(opts, WithMaxTokenSize(len(valid)-1)), Part: "token", Err: ErrInvalidToken},
+		{Name: "duplicate header key", Token: signRaw(t, `{"alg":"HS256","typ":"JWT","alg":"HS256"}`, `{"d":1}`),
+			Opts: strict, Part: "header", Err: ErrInvalidToken},
+		{Name: "duplicate nested key", Token: signRaw(t, `{"alg":"HS256","typ":"JWT"}`, `{"d":[{"a":1,"a":2}]}`),
+			Opts: strict, Part: "payload", Err: ErrInvalidToken},
+		// encoding/json would take the last "EXP", other libraries the expired "exp"
+		{Name: "duplicate key in another case", Token: signRaw(t, `{"alg":"HS256","typ":"JWT"}`, `{"d":1,"exp":1,"EXP":99999999999}`),
+			Opts: strict, Part: "payload", Err: ErrInvalidToken},
+		{Name: "duplicate header key in another case", Token: signRaw(t, `{"alg":"HS256","typ":"JWT","ALG":"HS256"}`, `{"d":1}`),
+			Opts: strict, Part: "header", Err: ErrInvalidToken},
+		{Name: "trailing whitespace", Token: signRaw(t, `{"alg":"HS256","typ":"JWT"}`, `{"d":1} `),
+			Opts: strict, Part: "payload", Err: ErrInvalidToken},
+		{Name: "trailing data", Token: signRaw(t, `{"alg":"HS256","typ":"JWT"}`, `{"d":1}{}`),
+			Opts: opts, Part: "payload", Err: ErrInvalidToken},
+		// "e30" and "e31" both decode to "{}" unless the trailing bits are checked
+		{Name: "non-canonical base64", Token: []byte("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.e31.sig"),
+			Opts: strict, Part: "payload", Err: ErrInvalidToken},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var data interface{}
+			err := Decode(tc.Token, &data, tc.Opts...)
+			if tc.Err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.Err)
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			require.Equal(t, tc.Part, validationErr.Part)
+		})
+	}
+
+	// the lenient mode keeps accepting duplicates, the last one wins
+	var data map[string]int
+	require.NoError(t, Decode(signRaw(t, `{"alg":"HS256","typ":"JWT"}`, `{"d":{"a":1,"a":2}}`), &data, opts...))
+	require.Equal(t, map[string]int{"a": 2}, data)
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, tc := range DecodeTestCases {
+		f.Add(tc.Token)
+	}
+	f.Add("eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJkIjoxfQ.")
+	f.Add("..")
+	f.Add("eyJ9.e30.")
+	f.Add(strings.Repeat("W", 64) + ".e30.")
+	f.Fuzz(func(t *testing.T, token string) {
+		var data interface{}
+		for _, opts := range [][]Option{
+			{WithSignMethod(HS256), WithKey([]byte("secret-key1"))},
+			{WithSignMethod(HS256), WithKey([]byte("secret-key1")), WithStrictJSON(), WithAllErrors(), WithMaxTokenSize(4096)},
+			{WithKeyProvider(NewKeySet()), WithFlatClaims()},
+		} {
+			_ = Decode([]byte(token), &data, opts...)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package jwt
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	b64 "encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+const defaultType = "JWT"
+
+// Header is the JOSE header of a token. Parameters without a field are kept in Extra.
+type Header struct {
+	Algorithm   SignMethod `json:"alg"`
+	Type        string     `json:"typ,omitempty"`
+	KeyID       string     `json:"kid,omitempty"`
+	ContentType string     `json:"cty,omitempty"`
+	// base64url SHA-1 thumbprint of the DER certificate
+	X509Thumbprint string `json:"x5t,omitempty"`
+	// RFC 7797: false means the payload is signed as is, not in base64; only for detached payloads
+	Base64 *bool `json:"b64,omitempty"`
+	// extensions the receiver must understand (RFC 7515, section 4.1.11)
+	Critical []string `json:"crit,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// the same fields without the JSON methods of Header
+type headerFields Header
+
+// a deep copy, so that changing it does not affect the token it came from
+func (h Header) clone() Header {
+	if h.Base64 != nil {
+		b64 := *h.Base64
+		h.Base64 = &b64
+	}
+	if h.Critical != nil {
+		h.Critical = append([]string(nil), h.Critical...)
+	}
+	if h.Extra != nil {
+		extra := make(map[string]json.RawMessage, len(h.Extra))
+		for name, value := range h.Extra {
+			extra[name] = append(json.RawMessage(nil), value...)
+		}
+		h.Extra = extra
+	}
+	return h
+}
+
+// names which can not be set by WithHeader
+var registeredHeaderNames = map[string]bool{
+	"alg": true, "typ": true, "kid": true, "cty": true, "x5t": true, "b64": true, "crit": true,
+}
+
+func (h Header) MarshalJSON() ([]byte, error) {
+	fields, err := json.Marshal(headerFields(h))
+	if err != nil || len(h.Extra) == 0 {
+		return fields, err
+	}
+	for name := range h.Extra {
+		if registeredHeaderNames[name] {
+			return nil, ErrConfigurationMalformed
+		}
+	}
+	// map keys are sorted, so the header is deterministic
+	extra, err := json.Marshal(h.Extra)
+	if err != nil {
+		return nil, err
+	}
+	fields[len(fields)-1] = ','
+	return append(fields, extra[1:]...), nil
+}
+
+func (h *Header) UnmarshalJSON(data []byte) error {
+	var fields headerFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for name := range all {
+		if registeredHeaderNames[name] {
+			delete(all, name)
+		}
+	}
+	if len(all) != 0 {
+		fields.Extra = all
+	}
+	*h = Header(fields)
+	return nil
+}
+
+func headerAssembly(configuration *config, signer Signer) (*Header, error) {
+	h := &Header{
+		Algorithm:      signer.Algorithm(),
+		Type:           defaultType,
+		KeyID:          configuration.KeyID,
+		ContentType:    configuration.ContentType,
+		X509Thumbprint: configuration.X509Thumbprint,
+	}
+	if configuration.Type != "" {
+		h.Type = configuration.Type
+	}
+	for name, value := range configuration.ExtraHeaders {
+		js, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if h.Extra == nil {
+			h.Extra = map[string]json.RawMessage{}
+		}
+		h.Extra[name] = js
+	}
+	return h, nil
+}
+
+// only "JWT" is accepted unless WithAllowedTypes is set
+func jwtTypeAllowed(configuration *config, typ string) bool {
+	allowed := configuration.AllowedTypes
+	if allowed == nil {
+		allowed = []string{defaultType}
+	}
+	for _, allowedType := range allowed {
+		if normalizeMediaType(allowedType) == normalizeMediaType(typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// RFC 7515, section 4.1.9: media types are case-insensitive and "application/" may be omitted
+func normalizeMediaType(typ string) string {
+	return strings.TrimPrefix(strings.ToLower(typ), "application/")
+}
+
+// X509Thumbprint is the "x5t" header value of the certificate
+func X509Thumbprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return b64.RawURLEncoding.EncodeToString(sum[:])
+}
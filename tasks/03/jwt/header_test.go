@@ -0,0 +1,94 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomHeader(t *testing.T) {
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key"))}
+	certPEM, err := os.ReadFile("testdata/cert.pem")
+	require.NoError(t, err)
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	token, err := Encode("data", append(opts, WithType("at+jwt"), WithContentType("json"), WithKeyID("k1"),
+		WithX509Thumbprint(X509Thumbprint(cert)), WithHeader("tenant", "acme"), WithHeader("ver", 2))...)
+	require.NoError(t, err)
+	encoded := bytes.Split(token, []byte("."))[0]
+	require.Equal(t, `{"alg":"HS256","typ":"at+jwt","kid":"k1","cty":"json","x5t":"`+X509Thumbprint(cert)+`","tenant":"acme","ver":2}`,
+		string(b64Bytes(string(encoded))))
+
+	parsed, err := Parse(token)
+	require.NoError(t, err)
+	require.Equal(t, Header{
+		Algorithm:      HS256,
+		Type:           "at+jwt",
+		KeyID:          "k1",
+		ContentType:    "json",
+		X509Thumbprint: X509Thumbprint(cert),
+		Extra:          map[string]json.RawMessage{"tenant": json.RawMessage(`"acme"`), "ver": json.RawMessage(`2`)},
+	}, parsed.Header)
+
+	// the default header is unchanged
+	plain, err := Encode("data", opts...)
+	require.NoError(t, err)
+	require.Equal(t, `{"alg":"HS256","typ":"JWT"}`, string(b64Bytes(string(bytes.Split(plain, []byte("."))[0]))))
+
+	_, err = Encode("data", append(opts, WithHeader("alg", "none"))...)
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode("data", append(opts, WithHeader("crit", []string{"exp"}))...)
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode("data", append(opts, WithHeader("bad", func() {}))...)
+	require.Error(t, err)
+}
+
+func TestAllowedTypes(t *testing.T) {
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key"))}
+	encodeWithType := func(typ string) []byte {
+		token, err := Encode("data", append(opts, WithType(typ))...)
+		require.NoError(t, err)
+		return token
+	}
+	noType := signRaw(t, `{"alg":"HS256"}`, `{"d":"data"}`)
+
+	testCases := []struct {
+		Token   []byte
+		Allowed []string
+		Err     error
+	}{
+		{Token: encodeWithType("JWT")},
+		{Token: encodeWithType("jwt")},
+		{Token: encodeWithType("application/JWT")},
+		{Token: encodeWithType("at+jwt"), Err: ErrInvalidToken},
+		{Token: encodeWithType("at+jwt"), Allowed: []string{"application/at+jwt"}},
+		{Token: encodeWithType("JWT"), Allowed: []string{"at+jwt"}, Err: ErrInvalidToken},
+		{Token: noType, Err: ErrInvalidToken},
+		{Token: noType, Allowed: []string{"JWT", ""}},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			decodeOpts := opts
+			if tc.Allowed != nil {
+				decodeOpts = append(opts[:len(opts):len(opts)], WithAllowedTypes(tc.Allowed...))
+			}
+			var data string
+			err := Decode(tc.Token, &data, decodeOpts...)
+			if tc.Err != nil {
+				require.ErrorIs(t, err, tc.Err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "data", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package jwt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectionHandler(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time {
+		return now
+	}
+	store := NewMemoryRevocationStore()
+	store.Clock = clock
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key")), WithIssuer("auth"), WithClock(clock)}
+	handler := IntrospectionHandler(BasicClientAuth(map[string]string{"gateway": "gateway-secret"}),
+		append(opts, WithRevocationStore(store))...)
+
+	access, err := Encode(map[string]string{"scope": "read write", "client_id": "app", "username": "dmitrii"},
+		append(opts, WithSubject("user-1"), WithAudience("api"), WithID("a-1"), WithIssuedAt(now), WithTTL(time.Minute))...)
+	require.NoError(t, err)
+	flat, err := Encode(map[string]string{"scope": "read"}, append(opts, WithFlatClaims(), WithTTL(time.Minute))...)
+	require.NoError(t, err)
+	revoked, err := Encode("data", append(opts, WithID("a-2"), WithTTL(time.Minute))...)
+	require.NoError(t, err)
+	require.NoError(t, store.Revoke("a-2", now.Add(time.Minute)))
+	expired, err := Encode("data", append(opts, WithExpires(now.Add(time.Second)))...)
+	require.NoError(t, err)
+	foreign, err := Encode("data", WithSignMethod(HS256), WithKey([]byte("other-key")), WithIssuer("auth"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Method string
+		Auth   []string
+		Form   url.Values
+		Status int
+		Body   string
+	}{
+		{Form: url.Values{"token": {string(access)}}, Status: http.StatusOK,
+			Body: `{"active":true,"scope":"read write","client_id":"app","username":"dmitrii",` +
+				`"exp":1060,"iss":"auth","sub":"user-1","aud":"api","iat":1000,"jti":"a-1"}`},
+		{Form: url.Values{"token": {string(flat)}, "token_type_hint": {"access_token"}}, Status: http.StatusOK,
+			Body: `{"active":true,"scope":"read","exp":1060,"iss":"auth"}`},
+		{Form: url.Values{"token": {string(revoked)}}, Status: http.StatusOK, Body: `{"active":false}`},
+		{Form: url.Values{"token": {string(expired)}}, Status: http.StatusOK, Body: `{"active":false}`},
+		{Form: url.Values{"token": {string(foreign)}}, Status: http.StatusOK, Body: `{"active":false}`},
+		{Form: url.Values{"token": {"garbage"}}, Status: http.StatusOK, Body: `{"active":false}`},
+		{Form: url.Values{}, Status: http.StatusBadRequest, Body: `{"error":"invalid_request"}`},
+		{Auth: []string{}, Form: url.Values{"token": {string(access)}}, Status: http.StatusUnauthorized, Body: `{"error":"invalid_client"}`},
+		{Auth: []string{"gateway", "wrong"}, Form: url.Values{"token": {string(access)}}, Status: http.StatusUnauthorized,
+			Body: `{"error":"invalid_client"}`},
+		{Method: http.MethodGet, Status: http.StatusMethodNotAllowed},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if tc.Method == "" {
+				tc.Method = http.MethodPost
+			}
+			now = time.Unix(1030, 0)
+			req := httptest.NewRequest(tc.Method, "/introspect", strings.NewReader(tc.Form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.Auth == nil {
+				req.SetBasicAuth("gateway", "gateway-secret")
+			} else if len(tc.Auth) == 2 {
+				req.SetBasicAuth(tc.Auth[0], tc.Auth[1])
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.Status, rec.Code)
+			if tc.Body != "" {
+				require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+				require.JSONEq(t, tc.Body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestIntrospectionHandlerCustomAuth(t *testing.T) {
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key"))}
+	token, err := Encode("data", opts...)
+	require.NoError(t, err)
+	// e.g. a bearer token of the resource server itself
+	handler := IntrospectionHandler(func(req *http.Request) error {
+		_, err := DecodeClaims([]byte(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")), opts...)
+		return err
+	}, opts...)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(url.Values{"token": {string(token)}}.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+string(token))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, map[string]interface{}{"active": true}, body)
+
+	resp, err = http.PostForm(server.URL, url.Values{"token": {string(token)}})
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,230 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// TokenPair is issued on login and on every refresh
+type TokenPair struct {
+	AccessToken  []byte
+	RefreshToken []byte
+}
+
+// "typ" of refresh tokens: they are not accepted where access tokens are checked with the
+// default WithAllowedTypes, and access tokens are not accepted by Refresh
+const refreshTokenType = "refresh+jwt"
+
+// payload of refresh tokens, so that an access token can not be exchanged
+type refreshClaims struct {
+	Family  string `json:"fam"`
+	Refresh bool   `json:"rt"`
+}
+
+type issuedToken struct {
+	ID      string
+	Expires time.Time
+}
+
+// all tokens descending from one Issue call
+type tokenFamily struct {
+	data    json.RawMessage
+	current string
+	used    map[string]bool
+	issued  []issuedToken
+	expires time.Time
+}
+
+// TokenIssuer issues short-lived access tokens together with long-lived refresh tokens.
+// A refresh token can be exchanged once: Refresh rotates it. Presenting an already used
+// refresh token again revokes the whole family (every access and refresh token issued
+// since the same Issue call), which defends against stolen refresh tokens.
+//
+// Access tokens are checked with Decode and the issuer options plus
+// WithRevocationStore(issuer.RevocationStore()).
+type TokenIssuer struct {
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	opts       []Option
+	store      RevocationStore
+	clock      func() time.Time
+
+	mu       sync.Mutex
+	families map[string]*tokenFamily
+}
+
+// NewTokenIssuer creates TokenIssuer. Options are used both to sign and to verify tokens
+// (e.g. WithSignMethod and WithKey, or WithPrivateKey), TTL and ID options are set by the issuer.
+// Without WithRevocationStore a MemoryRevocationStore with the WithClock clock is used.
+func NewTokenIssuer(accessTTL time.Duration, refreshTTL time.Duration, opts ...Option) *TokenIssuer {
+	configuration := assemblyJWTConfig(opts)
+	store := configuration.RevocationStore
+	if store == nil {
+		memory := NewMemoryRevocationStore()
+		memory.Clock = configuration.Clock
+		store = memory
+		opts = append(opts[:len(opts):len(opts)], WithRevocationStore(store))
+	}
+	return &TokenIssuer{
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		opts:       opts,
+		store:      store,
+		clock:      configuration.Clock,
+		families:   map[string]*tokenFamily{},
+	}
+}
+
+func (i *TokenIssuer) RevocationStore() RevocationStore {
+	return i.store
+}
+
+// Issue starts a new family on login, data goes to access tokens
+func (i *TokenIssuer) Issue(data interface{}) (*TokenPair, error) {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	familyID, err := randomTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.collectGarbage(clockNow(i.clock))
+	family := &tokenFamily{data: js, used: map[string]bool{}}
+	pair, err := i.issuePair(familyID, family)
+	if err != nil {
+		return nil, err
+	}
+	i.families[familyID] = family
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new pair. Reusing a refresh token
+// revokes its family and returns ErrRefreshTokenReused.
+func (i *TokenIssuer) Refresh(refreshToken []byte) (*TokenPair, error) {
+	familyID, jti, err := i.decodeRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	family, ok := i.families[familyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if family.used[jti] {
+		if err := i.revokeFamily(familyID, family); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if family.current != jti {
+		return nil, ErrInvalidToken
+	}
+	family.used[jti] = true
+	return i.issuePair(familyID, family)
+}
+
+// Revoke revokes the family of the refresh token, e.g. on logout
+func (i *TokenIssuer) Revoke(refreshToken []byte) error {
+	familyID, _, err := i.decodeRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	family, ok := i.families[familyID]
+	if !ok {
+		return ErrInvalidToken
+	}
+	return i.revokeFamily(familyID, family)
+}
+
+func (i *TokenIssuer) decodeRefreshToken(refreshToken []byte) (string, string, error) {
+	configuration := assemblyJWTConfig(append(i.opts[:len(i.opts):len(i.opts)], WithAllowedTypes(refreshTokenType)))
+	jwt, err := jwtDecodeVerified(configuration, refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	var claims refreshClaims
+	if err := jwtExtractData(configuration, jwt, &claims); err != nil {
+		return "", "", err
+	}
+	if !claims.Refresh || claims.Family == "" || jwt.Payload.ID == "" {
+		return "", "", ErrInvalidToken
+	}
+	return claims.Family, jwt.Payload.ID, nil
+}
+
+// must be called with i.mu held
+func (i *TokenIssuer) issuePair(familyID string, family *tokenFamily) (*TokenPair, error) {
+	now := clockNow(i.clock)
+	accessID, err := randomTokenID()
+	if err != nil {
+		return nil, err
+	}
+	refreshID, err := randomTokenID()
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := Encode(family.data, append(i.opts[:len(i.opts):len(i.opts)],
+		WithID(accessID), WithExpires(now.Add(i.accessTTL)))...)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := Encode(refreshClaims{Family: familyID, Refresh: true}, append(i.opts[:len(i.opts):len(i.opts)],
+		WithID(refreshID), WithExpires(now.Add(i.refreshTTL)), WithType(refreshTokenType))...)
+	if err != nil {
+		return nil, err
+	}
+
+	family.current = refreshID
+	family.expires = now.Add(i.refreshTTL)
+	// expired tokens need no revocation, a long-lived session must not pile them up
+	live := family.issued[:0]
+	for _, token := range family.issued {
+		if !now.After(token.Expires) {
+			live = append(live, token)
+		}
+	}
+	family.issued = append(live,
+		issuedToken{ID: accessID, Expires: now.Add(i.accessTTL)},
+		issuedToken{ID: refreshID, Expires: now.Add(i.refreshTTL)})
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// must be called with i.mu held
+func (i *TokenIssuer) revokeFamily(familyID string, family *tokenFamily) error {
+	for _, token := range family.issued {
+		if err := i.store.Revoke(token.ID, token.Expires); err != nil {
+			return err
+		}
+	}
+	delete(i.families, familyID)
+	return nil
+}
+
+// families are dropped once their last refresh token expires; must be called with i.mu held
+func (i *TokenIssuer) collectGarbage(now time.Time) {
+	for familyID, family := range i.families {
+		if now.After(family.expires) {
+			delete(i.families, familyID)
+		}
+	}
+}
+
+func randomTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
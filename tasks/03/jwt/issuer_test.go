@@ -0,0 +1,108 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenIssuer(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time {
+		return now
+	}
+
+	signOpts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key")), WithIssuer("auth"), WithClock(clock)}
+	issuer := NewTokenIssuer(time.Minute, time.Hour, signOpts...)
+	accessOpts := append(signOpts, WithRevocationStore(issuer.RevocationStore()))
+
+	login, err := issuer.Issue(map[string]string{"user": "dmitrii"})
+	require.NoError(t, err)
+	var data map[string]string
+	require.NoError(t, Decode(login.AccessToken, &data, accessOpts...))
+	require.Equal(t, map[string]string{"user": "dmitrii"}, data)
+
+	// an access token is not a refresh token and vice versa
+	_, err = issuer.Refresh(login.AccessToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	require.ErrorIs(t, Decode(login.RefreshToken, &data, accessOpts...), ErrInvalidToken)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+string(login.RefreshToken))
+	Authenticator(accessOpts...)(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		t.Error("a refresh token is accepted as an access token")
+	})).ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	now = now.Add(2 * time.Minute)
+	require.ErrorIs(t, Decode(login.AccessToken, &data, accessOpts...), ErrTokenExpired)
+
+	rotated, err := issuer.Refresh(login.RefreshToken)
+	require.NoError(t, err)
+	require.NotEqual(t, login.RefreshToken, rotated.RefreshToken)
+	require.NoError(t, Decode(rotated.AccessToken, &data, accessOpts...))
+	require.Equal(t, map[string]string{"user": "dmitrii"}, data)
+
+	second, err := issuer.Refresh(rotated.RefreshToken)
+	require.NoError(t, err)
+
+	// the stolen first refresh token is replayed: the whole family dies
+	_, err = issuer.Refresh(login.RefreshToken)
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+	require.ErrorIs(t, Decode(second.AccessToken, &data, accessOpts...), ErrTokenRevoked)
+	_, err = issuer.Refresh(second.RefreshToken)
+	require.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = issuer.Refresh(login.RefreshToken)
+	require.ErrorIs(t, err, ErrTokenRevoked)
+
+	// other families are not affected
+	other, err := issuer.Issue(map[string]string{"user": "ivan"})
+	require.NoError(t, err)
+	other, err = issuer.Refresh(other.RefreshToken)
+	require.NoError(t, err)
+	require.NoError(t, Decode(other.AccessToken, &data, accessOpts...))
+
+	// logout
+	require.NoError(t, issuer.Revoke(other.RefreshToken))
+	require.ErrorIs(t, Decode(other.AccessToken, &data, accessOpts...), ErrTokenRevoked)
+	_, err = issuer.Refresh(other.RefreshToken)
+	require.ErrorIs(t, err, ErrTokenRevoked)
+
+	// refresh token of another issuer
+	foreign, err := NewTokenIssuer(time.Minute, time.Hour, signOpts...).Issue("data")
+	require.NoError(t, err)
+	_, err = issuer.Refresh(foreign.RefreshToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	expired, err := issuer.Issue("data")
+	require.NoError(t, err)
+	now = now.Add(2 * time.Hour)
+	_, err = issuer.Refresh(expired.RefreshToken)
+	require.ErrorIs(t, err, ErrTokenExpired)
+}
+
+// a session which keeps refreshing keeps only the tokens which have not expired yet
+func TestTokenIssuerPrunesExpired(t *testing.T) {
+	now := time.Unix(1000, 0)
+	issuer := NewTokenIssuer(time.Minute, 10*time.Minute, WithSignMethod(HS256), WithKey([]byte("secret-key")),
+		WithClock(func() time.Time {
+			return now
+		}))
+	pair, err := issuer.Issue("data")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		now = now.Add(2 * time.Minute)
+		pair, err = issuer.Refresh(pair.RefreshToken)
+		require.NoError(t, err)
+	}
+	require.Len(t, issuer.families, 1)
+	for _, family := range issuer.families {
+		// the refresh tokens of the last 10 minutes inclusive and the current access token
+		require.Len(t, family.issued, 7)
+	}
+	require.NoError(t, issuer.Revoke(pair.RefreshToken))
+	require.LessOrEqual(t, issuer.RevocationStore().(*MemoryRevocationStore).Len(), 7)
+}
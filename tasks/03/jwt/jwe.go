@@ -0,0 +1,304 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	b64 "encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+)
+
+// KeyAlgorithm is JWE "alg": how the content encryption key is obtained
+type KeyAlgorithm string
+
+// ContentEncryption is JWE "enc": how the payload is encrypted
+type ContentEncryption string
+
+const (
+	// Direct uses the shared key as the content encryption key
+	Direct KeyAlgorithm = "dir"
+	// A256KW wraps a random content encryption key with the shared key (RFC 3394)
+	A256KW KeyAlgorithm = "A256KW"
+
+	A256GCM ContentEncryption = "A256GCM"
+)
+
+const (
+	jweGCMNonceSize = 12
+	jweGCMTagSize   = 16
+	jweKeySize      = 32
+)
+
+type jweHeader struct {
+	Algorithm   KeyAlgorithm      `json:"alg"`
+	Encryption  ContentEncryption `json:"enc"`
+	Type        string            `json:"typ,omitempty"`
+	ContentType string            `json:"cty,omitempty"`
+}
+
+type encryptionConfig struct {
+	Algorithm  KeyAlgorithm
+	Encryption ContentEncryption
+	Key        []byte
+}
+
+// EncodeEncrypted creates JWE compact serialization of the token. If a sign method or
+// a Signer is configured, the signed token (as made by Encode) is encrypted ("cty": "JWT"),
+// otherwise the payload itself is encrypted.
+func EncodeEncrypted(data interface{}, opts ...Option) ([]byte, error) {
+	jwtConfiguration := assemblyJWTConfig(opts)
+	encryption, err := jweEncryptionConfig(jwtConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	err = jwtConfigParse(jwtConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	jweHeaderFields := jweHeader{
+		Algorithm:  encryption.Algorithm,
+		Encryption: encryption.Encryption,
+		Type:       "JWT",
+	}
+	var plaintext []byte
+	if jwtConfiguration.Signer != nil || jwtConfiguration.SignMethod != "" {
+		plaintext, err = Encode(data, opts...)
+		jweHeaderFields.ContentType = "JWT"
+	} else {
+		plaintext, err = payloadAssembly(jwtConfiguration, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jweAssembly(encryption, jweHeaderFields, plaintext)
+}
+
+// DecodeEncrypted decrypts JWE created by EncodeEncrypted. Nested token is verified
+// by Decode with the same options; if signing is configured, a token which is only
+// encrypted is rejected.
+func DecodeEncrypted(token []byte, data interface{}, opts ...Option) error {
+	jwtConfiguration := assemblyJWTConfig(opts)
+	err := jwtCheckTokenSize(jwtConfiguration, token)
+	if err != nil {
+		return err
+	}
+	encryption, err := jweEncryptionConfig(jwtConfiguration)
+	if err != nil {
+		return err
+	}
+	jweHeaderFields, plaintext, err := jweDecrypt(encryption, token)
+	if err != nil {
+		return err
+	}
+	if jweHeaderFields.ContentType == "JWT" {
+		return Decode(plaintext, data, opts...)
+	}
+	if jwtConfiguration.Signer != nil || jwtConfiguration.Verifier != nil ||
+		jwtConfiguration.KeyProvider != nil || jwtConfiguration.SignMethod != "" {
+		// a signed token is expected, but the payload is only encrypted
+		return &ValidationError{Part: "cty", Err: ErrInvalidToken}
+	}
+
+	jwt := new(jwtParts)
+	err = jwtPayloadDecode(jwt, plaintext)
+	if err != nil {
+		return &ValidationError{Part: "payload", Err: ErrInvalidToken, Cause: err}
+	}
+	v := newValidation(jwtConfiguration)
+	jwtValidatePayload(jwtConfiguration, jwt, v)
+	err = v.err()
+	if err != nil {
+		return err
+	}
+	return jwtExtractData(jwtConfiguration, jwt, data)
+}
+
+func jweEncryptionConfig(configuration *config) (*encryptionConfig, error) {
+	encryption := configuration.Encryption
+	if encryption == nil || encryption.Encryption != A256GCM ||
+		(encryption.Algorithm != Direct && encryption.Algorithm != A256KW) {
+		return nil, ErrInvalidEncryption
+	}
+	if len(encryption.Key) != jweKeySize {
+		return nil, ErrInvalidKey
+	}
+	return encryption, nil
+}
+
+func jweAssembly(encryption *encryptionConfig, jweHeaderFields jweHeader, plaintext []byte) ([]byte, error) {
+	contentKey := encryption.Key
+	var encryptedKey []byte
+	if encryption.Algorithm == A256KW {
+		contentKey = make([]byte, jweKeySize)
+		if _, err := io.ReadFull(rand.Reader, contentKey); err != nil {
+			return nil, err
+		}
+		var err error
+		encryptedKey, err = aesKeyWrap(encryption.Key, contentKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	jweB64Header, err := b64JsonEncode(jweHeaderFields)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, jweGCMNonceSize)
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	// the encoded header is the additional authenticated data
+	sealed := gcm.Seal(nil, nonce, plaintext, []byte(jweB64Header))
+	ciphertext, tag := sealed[:len(sealed)-jweGCMTagSize], sealed[len(sealed)-jweGCMTagSize:]
+
+	var jwe bytes.Buffer
+	jwe.WriteString(jweB64Header)
+	for _, part := range [][]byte{encryptedKey, nonce, ciphertext, tag} {
+		jwe.WriteString(".")
+		jwe.WriteString(b64.RawURLEncoding.EncodeToString(part))
+	}
+	return jwe.Bytes(), nil
+}
+
+// JWE parts in order, as named by RFC 7516
+var jweParts = []string{"header", "encrypted_key", "iv", "ciphertext", "tag"}
+
+func jweDecrypt(encryption *encryptionConfig, token []byte) (*jweHeader, []byte, error) {
+	splittedParts := bytes.Split(token, []byte("."))
+	if len(splittedParts) != len(jweParts) {
+		return nil, nil, &ValidationError{Part: "token", Err: ErrInvalidToken}
+	}
+	jweHeaderFields := new(jweHeader)
+	rawHeader, err := b64DecodeCause(splittedParts[0])
+	if err == nil {
+		err = json.Unmarshal(rawHeader, jweHeaderFields)
+	}
+	if err != nil {
+		return nil, nil, &ValidationError{Part: "header", Err: ErrInvalidToken, Cause: err}
+	}
+	if jweHeaderFields.Algorithm != encryption.Algorithm {
+		return nil, nil, &ValidationError{Part: "alg", Err: ErrInvalidEncryption}
+	}
+	if jweHeaderFields.Encryption != encryption.Encryption {
+		return nil, nil, &ValidationError{Part: "enc", Err: ErrInvalidEncryption}
+	}
+	decodedParts := make([][]byte, len(jweParts)-1)
+	for i, part := range splittedParts[1:] {
+		decoded, err := b64DecodeCause(part)
+		if err != nil {
+			return nil, nil, &ValidationError{Part: jweParts[i+1], Err: ErrInvalidToken, Cause: err}
+		}
+		decodedParts[i] = decoded
+	}
+	encryptedKey, nonce, ciphertext, tag := decodedParts[0], decodedParts[1], decodedParts[2], decodedParts[3]
+	if len(nonce) != jweGCMNonceSize {
+		return nil, nil, &ValidationError{Part: "iv", Err: ErrInvalidToken}
+	}
+	if len(tag) != jweGCMTagSize {
+		return nil, nil, &ValidationError{Part: "tag", Err: ErrInvalidToken}
+	}
+
+	contentKey := encryption.Key
+	if encryption.Algorithm == Direct {
+		if len(encryptedKey) != 0 {
+			return nil, nil, &ValidationError{Part: "encrypted_key", Err: ErrInvalidToken}
+		}
+	} else {
+		contentKey, err = aesKeyUnwrap(encryption.Key, encryptedKey)
+		if err == nil && len(contentKey) != jweKeySize {
+			err = ErrDecryptionFailed
+		}
+		if err != nil {
+			return nil, nil, &ValidationError{Part: "encrypted_key", Err: err}
+		}
+	}
+
+	gcm, err := newGCM(contentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, append(ciphertext, tag...), splittedParts[0])
+	if err != nil {
+		return nil, nil, &ValidationError{Part: "ciphertext", Err: ErrDecryptionFailed, Cause: err}
+	}
+	return jweHeaderFields, plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return cipher.NewGCM(block)
+}
+
+// default initial value of RFC 3394, section 2.2.3.1
+var aesKeyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// AES Key Wrap (RFC 3394, section 2.2.1)
+func aesKeyWrap(kek []byte, key []byte) ([]byte, error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	n := len(key) / 8
+	wrapped := make([]byte, 8+len(key))
+	copy(wrapped, aesKeyWrapIV)
+	copy(wrapped[8:], key)
+
+	buffer := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buffer, wrapped[:8])
+			copy(buffer[8:], wrapped[8*i:8*i+8])
+			block.Encrypt(buffer, buffer)
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(wrapped[:8], binary.BigEndian.Uint64(buffer[:8])^t)
+			copy(wrapped[8*i:8*i+8], buffer[8:])
+		}
+	}
+	return wrapped, nil
+}
+
+// AES Key Unwrap (RFC 3394, section 2.2.2)
+func aesKeyUnwrap(kek []byte, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	n := len(wrapped)/8 - 1
+	key := make([]byte, len(wrapped))
+	copy(key, wrapped)
+
+	buffer := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buffer[:8], binary.BigEndian.Uint64(key[:8])^t)
+			copy(buffer[8:], key[8*i:8*i+8])
+			block.Decrypt(buffer, buffer)
+			copy(key[:8], buffer[:8])
+			copy(key[8*i:8*i+8], buffer[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(key[:8], aesKeyWrapIV) != 1 {
+		return nil, ErrDecryptionFailed
+	}
+	return key[8:], nil
+}
@@ -0,0 +1,357 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	b64 "encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sort"
+)
+
+// JWK is a JSON Web Key (RFC 7517) with members for "oct" (HMAC), "RSA", "EC" and "OKP" (Ed25519) keys
+type JWK struct {
+	KeyType   string     `json:"kty"`
+	KeyID     string     `json:"kid,omitempty"`
+	Algorithm SignMethod `json:"alg,omitempty"`
+	Use       string     `json:"use,omitempty"`
+
+	// symmetric key
+	K string `json:"k,omitempty"`
+	// RSA public key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP public key
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+
+	// private part of RSA, EC and OKP keys
+	D  string `json:"d,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+}
+
+// JWKSet is a JWK Set document
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeyVerifier is a Verifier whose key can be published in JWKS.
+// Built-in RS*, ES* and EdDSA verifiers implement it.
+type PublicKeyVerifier interface {
+	Verifier
+	PublicKey() crypto.PublicKey
+}
+
+const (
+	keyTypeOct = "oct"
+	keyTypeRSA = "RSA"
+	keyTypeEC  = "EC"
+	keyTypeOKP = "OKP"
+
+	curveEd25519 = "Ed25519"
+)
+
+var curveByName = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// sign method is implied by the curve, other key types require "alg"
+var signMethodByCurve = map[string]SignMethod{
+	"P-256":      ES256,
+	"P-384":      ES384,
+	"P-521":      ES512,
+	curveEd25519: EdDSA,
+}
+
+// NewJWK converts []byte, *rsa.PrivateKey, *rsa.PublicKey, *ecdsa.PrivateKey, *ecdsa.PublicKey,
+// ed25519.PrivateKey or ed25519.PublicKey to JWK. KeyID, Algorithm and Use are left empty.
+func NewJWK(key interface{}) (*JWK, error) {
+	switch key := key.(type) {
+	case []byte:
+		return &JWK{KeyType: keyTypeOct, K: b64.RawURLEncoding.EncodeToString(key)}, nil
+	case *rsa.PublicKey:
+		return &JWK{
+			KeyType: keyTypeRSA,
+			N:       b64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:       b64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *rsa.PrivateKey:
+		if len(key.Primes) != 2 {
+			return nil, ErrInvalidKey
+		}
+		jwk, _ := NewJWK(&key.PublicKey)
+		key.Precompute()
+		jwk.D = b64.RawURLEncoding.EncodeToString(key.D.Bytes())
+		jwk.P = b64.RawURLEncoding.EncodeToString(key.Primes[0].Bytes())
+		jwk.Q = b64.RawURLEncoding.EncodeToString(key.Primes[1].Bytes())
+		jwk.DP = b64.RawURLEncoding.EncodeToString(key.Precomputed.Dp.Bytes())
+		jwk.DQ = b64.RawURLEncoding.EncodeToString(key.Precomputed.Dq.Bytes())
+		jwk.QI = b64.RawURLEncoding.EncodeToString(key.Precomputed.Qinv.Bytes())
+		return jwk, nil
+	case *ecdsa.PublicKey:
+		params := key.Curve.Params()
+		if _, ok := curveByName[params.Name]; !ok {
+			return nil, ErrInvalidKey
+		}
+		// coordinates have the full length of the curve (RFC 7518, section 6.2.1.2)
+		size := (params.BitSize + 7) / 8
+		return &JWK{
+			KeyType: keyTypeEC,
+			Curve:   params.Name,
+			X:       b64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+			Y:       b64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case *ecdsa.PrivateKey:
+		jwk, err := NewJWK(&key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.D = b64.RawURLEncoding.EncodeToString(key.D.FillBytes(make([]byte, size)))
+		return jwk, nil
+	case ed25519.PublicKey:
+		if len(key) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKey
+		}
+		return &JWK{KeyType: keyTypeOKP, Curve: curveEd25519, X: b64.RawURLEncoding.EncodeToString(key)}, nil
+	case ed25519.PrivateKey:
+		if len(key) != ed25519.PrivateKeySize {
+			return nil, ErrInvalidKey
+		}
+		jwk, _ := NewJWK(key.Public())
+		jwk.D = b64.RawURLEncoding.EncodeToString(key.Seed())
+		return jwk, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// ParseJWK parses a single JWK document
+func ParseJWK(data []byte) (*JWK, error) {
+	jwk := new(JWK)
+	if err := json.Unmarshal(data, jwk); err != nil {
+		return nil, ErrInvalidKey
+	}
+	if _, err := jwk.Key(); err != nil {
+		return nil, err
+	}
+	return jwk, nil
+}
+
+// ParseJWKSet parses a JWK Set document. Keys of unsupported types are skipped
+// as required by RFC 7517, section 5.
+func ParseJWKSet(data []byte) (*JWKSet, error) {
+	var raw struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil || raw.Keys == nil {
+		return nil, ErrInvalidKey
+	}
+	set := &JWKSet{Keys: []JWK{}}
+	for _, rawKey := range raw.Keys {
+		jwk, err := ParseJWK(rawKey)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+	return set, nil
+}
+
+// Key converts JWK back to the key of the type accepted by NewSigner/NewVerifier:
+// private key if the private part is present, public key otherwise
+func (k *JWK) Key() (interface{}, error) {
+	switch k.KeyType {
+	case keyTypeOct:
+		return jwkBytes(k.K)
+	case keyTypeRSA:
+		return k.rsaKey()
+	case keyTypeEC:
+		return k.ecdsaKey()
+	case keyTypeOKP:
+		return k.ed25519Key()
+	}
+	return nil, ErrInvalidKey
+}
+
+// Public returns a copy of JWK without the private part, it fails for symmetric keys
+func (k *JWK) Public() (*JWK, error) {
+	if k.KeyType == keyTypeOct {
+		return nil, ErrInvalidKey
+	}
+	public := *k
+	public.D, public.P, public.Q, public.DP, public.DQ, public.QI = "", "", "", "", "", ""
+	return &public, nil
+}
+
+// Verifier creates a built-in Verifier for the key; "alg" is required unless it is implied by "crv"
+func (k *JWK) Verifier() (Verifier, error) {
+	key, err := k.Key()
+	if err != nil {
+		return nil, err
+	}
+	method := k.Algorithm
+	if method == "" {
+		method = signMethodByCurve[k.Curve]
+	}
+	return NewVerifier(method, key)
+}
+
+func (k *JWK) rsaKey() (interface{}, error) {
+	n, err := jwkBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := jwkBigInt(k.E)
+	if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, ErrInvalidKey
+	}
+	public := rsa.PublicKey{N: n, E: int(e.Int64())}
+	if k.D == "" {
+		return &public, nil
+	}
+	d, err := jwkBigInt(k.D)
+	if err != nil {
+		return nil, err
+	}
+	p, err := jwkBigInt(k.P)
+	if err != nil {
+		return nil, err
+	}
+	q, err := jwkBigInt(k.Q)
+	if err != nil {
+		return nil, err
+	}
+	private := &rsa.PrivateKey{PublicKey: public, D: d, Primes: []*big.Int{p, q}}
+	if private.Validate() != nil {
+		return nil, ErrInvalidKey
+	}
+	private.Precompute()
+	return private, nil
+}
+
+func (k *JWK) ecdsaKey() (interface{}, error) {
+	curve, ok := curveByName[k.Curve]
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	x, err := jwkBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := jwkBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, ErrInvalidKey
+	}
+	public := ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	if k.D == "" {
+		return &public, nil
+	}
+	d, err := jwkBigInt(k.D)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PrivateKey{PublicKey: public, D: d}, nil
+}
+
+func (k *JWK) ed25519Key() (interface{}, error) {
+	if k.Curve != curveEd25519 {
+		return nil, ErrInvalidKey
+	}
+	x, err := jwkBytes(k.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	if k.D == "" {
+		return ed25519.PublicKey(x), nil
+	}
+	seed, err := jwkBytes(k.D)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+func jwkBytes(value string) ([]byte, error) {
+	data, err := b64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, ErrInvalidKey
+	}
+	return data, nil
+}
+
+func jwkBigInt(value string) (*big.Int, error) {
+	data, err := jwkBytes(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// Verifier makes JWKSet a KeyProvider
+func (s *JWKSet) Verifier(kid string) (Verifier, error) {
+	for i := range s.Keys {
+		if s.Keys[i].KeyID == kid {
+			return s.Keys[i].Verifier()
+		}
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// JWKS exports public keys of the set, retired keys are included until they expire.
+// Symmetric keys and verifiers which are not PublicKeyVerifier are never published.
+func (s *KeySet) JWKS() *JWKSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	set := &JWKSet{Keys: []JWK{}}
+	for kid, entry := range s.keys {
+		verifier, ok := entry.verifier.(PublicKeyVerifier)
+		if !ok || (entry.retireAt != nil && clockNow(s.Clock).After(*entry.retireAt)) {
+			continue
+		}
+		jwk, err := NewJWK(verifier.PublicKey())
+		if err != nil {
+			continue
+		}
+		jwk.KeyID = kid
+		jwk.Algorithm = verifier.Algorithm()
+		jwk.Use = "sig"
+		set.Keys = append(set.Keys, *jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+	return set
+}
+
+// JWKSHandler serves the current public keys of the set, it is meant to be mounted
+// on /.well-known/jwks.json
+func JWKSHandler(keys *KeySet) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			rw.Header().Set("Allow", "GET, HEAD")
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		js, err := json.Marshal(keys.JWKS())
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/jwk-set+json")
+		_, _ = rw.Write(js)
+	})
+}
@@ -0,0 +1,280 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKRoundTrip(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey := mustPrivateKey("testdata/rsa_pkcs1.pem")
+
+	keys := []interface{}{
+		[]byte("secret-key"),
+		rsaKey,
+		mustPublicKey("testdata/rsa_pkix.pem"),
+		p384,
+		&p384.PublicKey,
+		edPrivate,
+		edPublic,
+	}
+	for _, key := range keys {
+		jwk, err := NewJWK(key)
+		require.NoError(t, err)
+		js, err := json.Marshal(jwk)
+		require.NoError(t, err)
+		parsed, err := ParseJWK(js)
+		require.NoError(t, err)
+		parsedKey, err := parsed.Key()
+		require.NoError(t, err)
+		require.Equal(t, key, parsedKey, string(js))
+	}
+
+	public, err := (&JWK{KeyType: "oct", K: "c2VjcmV0"}).Public()
+	require.ErrorIs(t, err, ErrInvalidKey)
+	require.Nil(t, public)
+	jwk, err := NewJWK(edPrivate)
+	require.NoError(t, err)
+	public, err = jwk.Public()
+	require.NoError(t, err)
+	publicKey, err := public.Key()
+	require.NoError(t, err)
+	require.Equal(t, edPublic, publicKey)
+}
+
+// JWK documents of RFC 7515 (appendix A.3) and RFC 8037 (appendix A.2) verify the RFC tokens
+func TestJWKRFCVectors(t *testing.T) {
+	set, err := ParseJWKSet([]byte(`{"keys": [
+		{"kty":"EC","kid":"es256","crv":"P-256",
+		 "x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU",
+		 "y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"},
+		{"kty":"OKP","kid":"ed25519","crv":"Ed25519",
+		 "x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+		{"kty":"OKP","kid":"x25519","crv":"X25519",
+		 "x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"},
+		{"kty":"unknown","kid":"unknown"}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, set.Keys, 2)
+
+	verifier, err := set.Verifier("es256")
+	require.NoError(t, err)
+	require.Equal(t, ES256, verifier.Algorithm())
+	require.NoError(t, verifier.Verify(
+		[]byte("eyJhbGciOiJFUzI1NiJ9.eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ"),
+		b64Bytes("DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q")))
+
+	verifier, err = set.Verifier("ed25519")
+	require.NoError(t, err)
+	require.Equal(t, EdDSA, verifier.Algorithm())
+	require.NoError(t, verifier.Verify(
+		[]byte("eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc"),
+		b64Bytes("hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg")))
+
+	_, err = set.Verifier("x25519")
+	require.ErrorIs(t, err, ErrUnknownKeyID)
+
+	_, err = ParseJWK([]byte(`{"kty":"EC","crv":"P-256","x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"AAAA"}`))
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = ParseJWKSet([]byte(`{"keys": 1}`))
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestJWKSHandlerAndRemoteKeySet(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	firstKey := mustPrivateKey("testdata/rsa_pkcs8.pem")
+	secondKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	firstVerifier, err := NewVerifier(RS256, firstKey)
+	require.NoError(t, err)
+	secondVerifier, err := NewVerifier(ES256, secondKey)
+	require.NoError(t, err)
+	hmacVerifier, err := NewVerifier(HS256, []byte("secret-key"))
+	require.NoError(t, err)
+
+	keys := NewKeySet()
+	keys.Add("first", firstVerifier)
+	keys.Add("hmac", hmacVerifier)
+
+	var requests int32
+	mux := http.NewServeMux()
+	mux.Handle("/.well-known/jwks.json", JWKSHandler(keys))
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		mux.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/.well-known/jwks.json")
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/jwk-set+json", resp.Header.Get("Content-Type"))
+	var published JWKSet
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&published))
+	// the HMAC secret is never published
+	require.Len(t, published.Keys, 1)
+	require.Equal(t, "first", published.Keys[0].KeyID)
+	require.Equal(t, RS256, published.Keys[0].Algorithm)
+	require.Empty(t, published.Keys[0].D)
+
+	remote := NewRemoteKeySet(server.URL+"/.well-known/jwks.json", server.Client())
+	remote.MinRefreshInterval = time.Minute
+	remote.Clock = func() time.Time {
+		return now
+	}
+	atomic.StoreInt32(&requests, 0)
+
+	firstToken, err := Encode("first", WithSignMethod(RS256), WithPrivateKey(firstKey), WithKeyID("first"))
+	require.NoError(t, err)
+	var data string
+	require.NoError(t, Decode(firstToken, &data, WithKeyProvider(remote)))
+	require.NoError(t, Decode(firstToken, &data, WithKeyProvider(remote)))
+	require.Equal(t, "first", data)
+	require.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	// a new key appears on the server: refetched on unknown kid, but not too often
+	keys.Add("second", secondVerifier)
+	secondToken, err := Encode("second", WithSignMethod(ES256), WithPrivateKey(secondKey), WithKeyID("second"))
+	require.NoError(t, err)
+	require.ErrorIs(t, Decode(secondToken, &data, WithKeyProvider(remote)), ErrUnknownKeyID)
+	require.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	now = now.Add(time.Minute)
+	require.NoError(t, Decode(secondToken, &data, WithKeyProvider(remote)))
+	require.Equal(t, "second", data)
+	require.EqualValues(t, 2, atomic.LoadInt32(&requests))
+
+	// stale set is used when the server is down
+	server.Close()
+	now = now.Add(2 * defaultJWKSCacheTTL)
+	require.NoError(t, Decode(firstToken, &data, WithKeyProvider(remote)))
+
+	broken := NewRemoteKeySet(server.URL+"/.well-known/jwks.json", server.Client())
+	require.Error(t, Decode(firstToken, &data, WithKeyProvider(broken)))
+}
+
+// a hung JWKS server does not block tokens with a cached kid, and concurrent misses share one fetch
+func TestRemoteKeySetSlowServer(t *testing.T) {
+	now := time.Unix(1000, 0)
+	hmacVerifier, err := NewVerifier(HS256, []byte("secret-key"))
+	require.NoError(t, err)
+	rsaKey := mustPrivateKey("testdata/rsa_pkcs8.pem")
+	rsaVerifier, err := NewVerifier(RS256, rsaKey)
+	require.NoError(t, err)
+	keys := NewKeySet()
+	keys.Add("first", rsaVerifier)
+	keys.Add("hmac", hmacVerifier)
+
+	var requests int32
+	hang := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			<-hang
+		}
+		JWKSHandler(keys).ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	defer close(hang)
+
+	require.NotZero(t, NewRemoteKeySet(server.URL, nil).client.Timeout)
+	remote := NewRemoteKeySet(server.URL, server.Client())
+	remote.Clock = func() time.Time {
+		return now
+	}
+	token, err := Encode("first", WithSignMethod(RS256), WithPrivateKey(rsaKey), WithKeyID("first"))
+	require.NoError(t, err)
+	var data string
+	require.NoError(t, Decode(token, &data, WithKeyProvider(remote)))
+
+	// the set expires, the refetch hangs
+	now = now.Add(2 * defaultJWKSCacheTTL)
+	unknown, err := Encode("unknown", WithSignMethod(RS256), WithPrivateKey(rsaKey), WithKeyID("unknown"))
+	require.NoError(t, err)
+	waiting := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			var data string
+			waiting <- Decode(unknown, &data, WithKeyProvider(remote))
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, Decode(token, &data, WithKeyProvider(remote)))
+	}
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&requests) == 2
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case err := <-waiting:
+		t.Fatalf("an unknown kid is resolved before the fetch: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// a failing server is not asked more often than MinRefreshInterval, whatever the kids
+func TestRemoteKeySetFailingServer(t *testing.T) {
+	now := time.Unix(1000, 0)
+	rsaKey := mustPrivateKey("testdata/rsa_pkcs8.pem")
+	rsaVerifier, err := NewVerifier(RS256, rsaKey)
+	require.NoError(t, err)
+	keys := NewKeySet()
+	keys.Add("first", rsaVerifier)
+
+	var requests int32
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		JWKSHandler(keys).ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+
+	remote := NewRemoteKeySet(server.URL, server.Client())
+	remote.Clock = func() time.Time {
+		return now
+	}
+	// never fetched: the error of the last attempt is returned until the interval passes
+	atomic.StoreInt32(&failing, 1)
+	for i := 0; i < 50; i++ {
+		_, err := remote.Verifier("first")
+		require.Error(t, err)
+	}
+	require.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&failing, 0)
+	now = now.Add(remote.MinRefreshInterval)
+	_, err = remote.Verifier("first")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, atomic.LoadInt32(&requests))
+
+	// random kids while the server is down
+	atomic.StoreInt32(&failing, 1)
+	now = now.Add(remote.MinRefreshInterval)
+	for i := 0; i < 50; i++ {
+		_, err := remote.Verifier(strconv.Itoa(i))
+		require.Error(t, err)
+	}
+	require.EqualValues(t, 3, atomic.LoadInt32(&requests))
+	_, err = remote.Verifier("first")
+	require.NoError(t, err)
+}
@@ -0,0 +1,155 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSCacheTTL           = time.Hour
+	defaultJWKSMinRefreshInterval = 10 * time.Second
+	defaultJWKSFetchTimeout       = 10 * time.Second
+
+	// a JWKS document is small, anything bigger is not a key set
+	maxJWKSSize = 1 << 20
+)
+
+// RemoteKeySet is a KeyProvider backed by a JWKS URL. The set is cached for CacheTTL;
+// a token with an unknown kid triggers an earlier refetch, but not more often than
+// MinRefreshInterval. If refetching fails, the cached set keeps being used.
+//
+// There is at most one fetch at a time and the lock is not held during it: while the
+// expired set is refetched, tokens with a known kid are verified with the cached one.
+type RemoteKeySet struct {
+	CacheTTL           time.Duration
+	MinRefreshInterval time.Duration
+	// Clock is the source of current time, nil means time.Now
+	Clock func() time.Time
+
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      *JWKSet
+	fetchedAt time.Time
+	// the last fetch started, successful or not, and its error: a failing server is
+	// not asked more often than MinRefreshInterval either
+	attemptedAt time.Time
+	attemptErr  error
+	// the fetch in flight, nil if there is none
+	fetching *jwksFetch
+}
+
+type jwksFetch struct {
+	done chan struct{}
+	// set before done is closed
+	err error
+}
+
+// NewRemoteKeySet creates RemoteKeySet, nil client means a client with a timeout
+// of a few seconds (http.DefaultClient has none, a hung server would block verification)
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: defaultJWKSFetchTimeout}
+	}
+	return &RemoteKeySet{
+		CacheTTL:           defaultJWKSCacheTTL,
+		MinRefreshInterval: defaultJWKSMinRefreshInterval,
+		url:                url,
+		client:             client,
+	}
+}
+
+func (s *RemoteKeySet) Verifier(kid string) (Verifier, error) {
+	now := clockNow(s.Clock)
+	s.mu.Lock()
+	var fetch *jwksFetch
+	if (s.keys == nil || now.Sub(s.fetchedAt) >= s.CacheTTL) && s.mayFetch(now) {
+		fetch = s.startFetch(now)
+	}
+	keys, attemptErr := s.keys, s.attemptErr
+	s.mu.Unlock()
+
+	if keys == nil && fetch == nil {
+		return nil, attemptErr
+	}
+	if keys != nil {
+		verifier, err := keys.Verifier(kid)
+		if !errors.Is(err, ErrUnknownKeyID) {
+			return verifier, err
+		}
+		s.mu.Lock()
+		if fetch == nil && now.Sub(s.fetchedAt) >= s.MinRefreshInterval && s.mayFetch(now) {
+			fetch = s.startFetch(now)
+		}
+		s.mu.Unlock()
+		if fetch == nil {
+			return nil, err
+		}
+	}
+
+	<-fetch.done
+	if fetch.err != nil {
+		return nil, fetch.err
+	}
+	s.mu.Lock()
+	keys = s.keys
+	s.mu.Unlock()
+	return keys.Verifier(kid)
+}
+
+// a fetch is in flight to join, or the last one was started long enough ago;
+// must be called with s.mu held
+func (s *RemoteKeySet) mayFetch(now time.Time) bool {
+	return s.fetching != nil || s.attemptedAt.IsZero() || now.Sub(s.attemptedAt) >= s.MinRefreshInterval
+}
+
+// joins the fetch in flight or starts a new one; must be called with s.mu held
+func (s *RemoteKeySet) startFetch(now time.Time) *jwksFetch {
+	if s.fetching != nil {
+		return s.fetching
+	}
+	fetch := &jwksFetch{done: make(chan struct{})}
+	s.fetching = fetch
+	s.attemptedAt = now
+	go func() {
+		keys, err := s.download()
+		s.mu.Lock()
+		if err == nil {
+			s.keys = keys
+			s.fetchedAt = now
+		}
+		s.attemptErr = err
+		s.fetching = nil
+		s.mu.Unlock()
+		fetch.err = err
+		close(fetch.done)
+	}()
+	return fetch
+}
+
+func (s *RemoteKeySet) download() (*JWKSet, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks %s: %w", s.url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks %s: unexpected status %d", s.url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks %s: %w", s.url, err)
+	}
+	keys, err := ParseJWKSet(body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks %s: %w", s.url, err)
+	}
+	return keys, nil
+}
@@ -3,38 +3,33 @@ package jwt
 import (
         "errors"
         "time"
-        "hash"
-        "crypto/hmac"
-        "crypto/sha256"
-        "crypto/sha512"
+        "crypto"
         "bytes"
         "encoding/json"
         b64 "encoding/base64"
 )
 
 
-type header struct {
-        Algorithm       SignMethod      `json:"alg"`
-        Type            string          `json:"typ"`
-}
-
-// our payload format has structure {'d': something...., 'exp' : time...}
+// our payload format has structure {'d': something...., 'exp' : time..., other registered claims}
 type payload struct {
         Data            interface{}	`json:"d"`
-        ExpTime         int64           `json:"exp,omitempty"`
+        registeredClaims
 }
 
 // special for decoding
 type payloadDecoded struct {
         Data            json.RawMessage	`json:"d"`	// output
-        ExpTime         int64           `json:"exp,omitempty"`
+        registeredClaims
 }
 
 // special for decoding
 type jwtParts struct {
-        Header          header
+        Header          Header
         Payload		payloadDecoded
+        RawPayload      json.RawMessage
+        SigningInput    []byte
         Signature       []byte
+        Detached        bool
 }
 
 type SignMethod string
@@ -42,22 +37,36 @@ type SignMethod string
 const (
         HS256 SignMethod = "HS256"
         HS512 SignMethod = "HS512"
+        RS256 SignMethod = "RS256"
+        RS384 SignMethod = "RS384"
+        RS512 SignMethod = "RS512"
+        ES256 SignMethod = "ES256"
+        ES384 SignMethod = "ES384"
+        ES512 SignMethod = "ES512"
+        EdDSA SignMethod = "EdDSA"
 )
 
-// convinient for code extension
-var HASHSIGNFUNCTION = map[SignMethod] func() (hash.Hash) {
-        HS256: sha256.New,
-        HS512: sha512.New,
-}
-
 var (
         ErrInvalidSignMethod            = errors.New("invalid sign method")
         ErrSignatureInvalid             = errors.New("signature invalid")
         ErrTokenExpired                 = errors.New("token expired")
+        ErrTokenNotValidYet             = errors.New("token not valid yet")
+        ErrTokenUsedBeforeIssued        = errors.New("token used before issued")
+        ErrInvalidIssuer                = errors.New("invalid issuer")
+        ErrInvalidSubject               = errors.New("invalid subject")
+        ErrSubjectMissing               = errors.New("subject missing")
+        ErrInvalidAudience              = errors.New("invalid audience")
         ErrSignMethodMismatched         = errors.New("sign method mismatched")
         ErrConfigurationMalformed       = errors.New("configuration malformed")
         ErrInvalidToken                 = errors.New("invalid token")
         ErrInternalError                = errors.New("internal error")
+        ErrInvalidKey                   = errors.New("invalid key")
+        ErrInvalidPayload               = errors.New("invalid payload")
+        ErrUnknownKeyID                 = errors.New("unknown key id")
+        ErrInvalidEncryption            = errors.New("invalid encryption method")
+        ErrDecryptionFailed             = errors.New("decryption failed")
+        ErrTokenRevoked                 = errors.New("token revoked")
+        ErrRefreshTokenReused           = errors.New("refresh token reused")
 )
 
 func assemblyJWTConfig(opts []Option) (*config) {
@@ -70,7 +79,7 @@ func assemblyJWTConfig(opts []Option) (*config) {
 
 func jwtConfigParse(configuration *config) (error) {
         if ((configuration.Expires != nil) && 
-           ((configuration.TTL != nil) || configuration.Expires.Before(timeFunc()))) {
+           ((configuration.TTL != nil) || configuration.Expires.Before(configuration.now()))) {
                 return ErrConfigurationMalformed
         }
         return nil
@@ -84,65 +93,92 @@ func b64JsonEncode(data interface{}) (string, error) {
         return b64.RawURLEncoding.EncodeToString(js), nil
 }
 
-func b64JsonDecode(data []byte, dstData interface{}) (error) {
+func b64DecodeCause(data []byte) ([]byte, error) {
 	decodedB64Data := make([]byte, b64.RawURLEncoding.DecodedLen(len(data)))
 	_, err := b64.RawURLEncoding.Decode(decodedB64Data, data)
         if err != nil {
-                return ErrInvalidToken
+                return nil, err
         }
-        err = json.Unmarshal(decodedB64Data, dstData)
+        return decodedB64Data, nil
+}
+
+func encodedHeaderAssembly(configuration *config, signer Signer) (string, error) {
+        h, err := headerAssembly(configuration, signer)
         if err != nil {
-                return ErrInvalidToken
+                return "", err
         }
-        return nil
+        return b64JsonEncode(h)
 }
 
-func encodedHeaderAssembly(configuration *config) (string) {
-        // there will be no error
-        jsonB64Header, _ := b64JsonEncode(header {
-                Algorithm:      configuration.SignMethod,
-                Type:           "JWT",
+func payloadAssembly(configuration *config, data interface{}) ([]byte, error) {
+        if configuration.FlatClaims {
+                claims, err := flatClaimsAssembly(configuration, data)
+                if err != nil {
+                        return nil, err
+                }
+                return json.Marshal(claims)
+        }
+        return json.Marshal(payload {
+                Data:                   data,
+                registeredClaims:       registeredClaimsAssembly(configuration),
         })
-        return jsonB64Header
 }
 
-func encodedPayloadAssembly(configuration *config, data interface{}) (string) {
-        var expTime int64
-        if configuration.Expires != nil {
-                expTime = configuration.Expires.Unix()
+func encodedPayloadAssembly(configuration *config, data interface{}) (string, error) {
+        js, err := payloadAssembly(configuration, data)
+        if err != nil {
+                return "", err
         }
-        if configuration.TTL != nil {
-                expTime = timeFunc().Add(*configuration.TTL).Unix()
+        return b64.RawURLEncoding.EncodeToString(js), nil
+}
+
+// configured Signer or the built-in one for configured method and key
+func jwtSigner(configuration *config) (Signer, error) {
+        if configuration.Signer != nil {
+                return configuration.Signer, nil
+        }
+        if configuration.PrivateKey != nil {
+                return NewSigner(configuration.SignMethod, configuration.PrivateKey)
         }
+        return NewSigner(configuration.SignMethod, configuration.Key)
+}
 
-        // else expTime will be 0 by default
-        // there will be no error
-        jsonB64Payload, _ := b64JsonEncode(payload {
-                Data:           data,
-                ExpTime:        expTime,
-        })
-        return jsonB64Payload
+// configured Verifier, the one resolved by key provider or the built-in one for configured method and key
+func jwtVerifier(configuration *config, keyID string) (Verifier, error) {
+        if configuration.Verifier != nil {
+                return configuration.Verifier, nil
+        }
+        if configuration.KeyProvider != nil {
+                return configuration.KeyProvider.Verifier(keyID)
+        }
+        if _, ok := hmacSignHash[configuration.SignMethod]; ok {
+                return NewVerifier(configuration.SignMethod, configuration.Key)
+        }
+        return NewVerifier(configuration.SignMethod, verificationKey(configuration))
 }
 
-func hmacSignature(hashMethod func() (hash.Hash), key []byte, data []byte) []byte {
-        mac := hmac.New(hashMethod, key)
-        mac.Write(data)
-        return mac.Sum(nil)
+// public key for asymmetric methods; falls back to the public part of the private key
+func verificationKey(configuration *config) (crypto.PublicKey) {
+        if configuration.PublicKey != nil {
+                return configuration.PublicKey
+        }
+        if signer, ok := configuration.PrivateKey.(crypto.Signer); ok {
+                return signer.Public()
+        }
+        return nil
 }
 
-func jwtAssembly(configuration *config, jwtB64Header string, jwtB64Payload string) ([]byte, error) {
+func jwtAssembly(signer Signer, jwtB64Header string, jwtB64Payload string) ([]byte, error) {
         var jwt bytes.Buffer
         jwt.WriteString(jwtB64Header)
         jwt.WriteString(".")
         jwt.WriteString(jwtB64Payload)
 
-        hashMethod, ok := HASHSIGNFUNCTION[configuration.SignMethod]
-        if !ok {
-                return nil, ErrInvalidSignMethod
+        jwtSignatureBytes, err := signer.Sign(jwt.Bytes())
+        if err != nil {
+                return nil, err
         }
-
-        jwtHMACbytes := hmacSignature(hashMethod, configuration.Key, jwt.Bytes())
-        jwtSign := b64.RawURLEncoding.EncodeToString(jwtHMACbytes)
+        jwtSign := b64.RawURLEncoding.EncodeToString(jwtSignatureBytes)
 
         jwt.WriteString(".")
         jwt.WriteString(jwtSign)
@@ -155,120 +191,227 @@ func Encode(data interface{}, opts ...Option) ([]byte, error) {
         if err != nil {
                 return nil, err
         }
-        jwtHeader := encodedHeaderAssembly(jwtConfiguration)
-        jwtPayload := encodedPayloadAssembly(jwtConfiguration, data)
+        signer, err := jwtSigner(jwtConfiguration)
+        if err != nil {
+                return nil, err
+        }
+        jwtHeader, err := encodedHeaderAssembly(jwtConfiguration, signer)
+        if err != nil {
+                return nil, err
+        }
+        jwtPayload, err := encodedPayloadAssembly(jwtConfiguration, data)
+        if err != nil {
+                return nil, err
+        }
 
-        jwt, err := jwtAssembly(jwtConfiguration, jwtHeader, jwtPayload)
+        jwt, err := jwtAssembly(signer, jwtHeader, jwtPayload)
         if err != nil {
                 return nil, err
         }
         return jwt, nil
 }
 
-func jwtB64DecodeParts(splittedParts [][]byte) (*jwtParts, error) {
+func jwtB64DecodeParts(configuration *config, splittedParts [][]byte) (*jwtParts, error) {
         jwtparts := new(jwtParts)
 
         // check header
-	err := b64JsonDecode(splittedParts[0], &jwtparts.Header)
+        err := jwtHeaderDecode(configuration, jwtparts, splittedParts[0])
         if err != nil {
-                return nil, ErrInvalidToken
+                return nil, err
         }
 
         // check payload
-        err = b64JsonDecode(splittedParts[1], &jwtparts.Payload)
+        rawPayload, err := b64DecodeSegment(configuration, splittedParts[1])
+        if err == nil {
+                err = jwtPayloadDecode(jwtparts, rawPayload)
+        }
+        if err == nil && configuration.StrictJSON {
+                err = strictJSONCheck(rawPayload)
+        }
         if err != nil {
-                return nil, ErrInvalidToken
+                return nil, &ValidationError{Part: "payload", Err: ErrInvalidToken, Cause: err}
         }
 
         // decode hmac
-	jwtparts.Signature = make([]byte, b64.RawURLEncoding.DecodedLen(len(splittedParts[2])))
-	_, err = b64.RawURLEncoding.Decode(jwtparts.Signature, splittedParts[2])
-	if err != nil {
-                return nil, ErrSignatureInvalid
+        err = jwtSignatureDecode(jwtparts, splittedParts[2])
+        if err != nil {
+                return nil, err
         }
         return jwtparts, nil
 }
 
-func jwtTokenSplitDecode(token []byte) (*jwtParts, error) {
+func jwtHeaderDecode(configuration *config, jwt *jwtParts, segment []byte) (error) {
+        rawHeader, err := b64DecodeSegment(configuration, segment)
+        if err == nil {
+                err = json.Unmarshal(rawHeader, &jwt.Header)
+        }
+        if err == nil && configuration.StrictJSON {
+                err = strictJSONCheck(rawHeader)
+        }
+        if err != nil {
+                return &ValidationError{Part: "header", Err: ErrInvalidToken, Cause: err}
+        }
+        return nil
+}
+
+func jwtSignatureDecode(jwt *jwtParts, segment []byte) (error) {
+        var err error
+        jwt.Signature, err = b64DecodeCause(segment)
+        if err != nil {
+                return &ValidationError{Part: "signature", Err: ErrSignatureInvalid, Cause: err}
+        }
+        return nil
+}
+
+// raw JSON is kept for flat claims
+func jwtPayloadDecode(jwt *jwtParts, rawPayload []byte) (error) {
+        jwt.RawPayload = rawPayload
+        return json.Unmarshal(rawPayload, &jwt.Payload)
+}
+
+func jwtTokenSplitDecode(configuration *config, token []byte) (*jwtParts, error) {
+        err := jwtCheckTokenSize(configuration, token)
+        if err != nil {
+                return nil, err
+        }
         splittedParts := bytes.Split(token, []byte("."))
         // incorrent amount of partitions
         if len(splittedParts) != 3 {
-                return nil, ErrInvalidToken
+                return nil, &ValidationError{Part: "token", Err: ErrInvalidToken}
         }
-        return jwtB64DecodeParts(splittedParts)
+        jwt, err := jwtB64DecodeParts(configuration, splittedParts)
+        if err != nil {
+                return nil, err
+        }
+	// work with token []byte to avoid redundant copying
+        jwt.SigningInput = token[:bytes.LastIndex(token, []byte("."))]
+        return jwt, nil
 }
 
 // true -> already expired
-func jwtIsAlreadyExpired(jwt *jwtParts) (error) {
+func jwtIsAlreadyExpired(configuration *config, jwt *jwtParts) (error) {
 	// first - default variable when ExpTime is not located in JWT
-        if jwt.Payload.ExpTime != 0 && timeFunc().After(time.Unix(jwt.Payload.ExpTime, 0)) {
+        expires := time.Unix(int64(jwt.Payload.ExpTime), 0).Add(configuration.Leeway)
+        if jwt.Payload.ExpTime != 0 && configuration.now().After(expires) {
 		return ErrTokenExpired
 	}
 	return nil
 }
 
-func jwtCheckHeader(configuration *config, jwt *jwtParts) (error) {
-        if jwt.Header.Type != "JWT" {
-                return ErrInvalidToken
+func jwtCheckHeader(configuration *config, verifier Verifier, jwt *jwtParts, v *validation) {
+        if !jwtTypeAllowed(configuration, jwt.Header.Type) {
+                v.add("typ", ErrInvalidToken, nil)
         }
 
-        if jwt.Header.Algorithm != configuration.SignMethod {
-                return ErrSignMethodMismatched
-        }
-        _, ok := HASHSIGNFUNCTION[jwt.Header.Algorithm]
-        if !ok {
-                return ErrInvalidSignMethod
+        if v.proceed() && jwt.Header.Algorithm != verifier.Algorithm() {
+                v.add("alg", ErrSignMethodMismatched, nil)
         }
-        return nil
 }
 
-// function takes slice to avoid redundant copying
-func jwtSignatureValidation(configuration *config, expectedSignature []byte, headerAndPayload []byte) (error) {
-        hashMethod, _ := HASHSIGNFUNCTION[configuration.SignMethod]
-        if bytes.Compare(expectedSignature, hmacSignature(hashMethod, configuration.Key, headerAndPayload)) != 0 {
-                return ErrSignatureInvalid
-	}
-	return nil
+func Decode(token []byte, data interface{}, opts ...Option) error {
+        jwtConfiguration := assemblyJWTConfig(opts)
+        jwt, err := jwtDecodeVerified(jwtConfiguration, token)
+        if err != nil {
+                return err
+        }
+        return jwtExtractData(jwtConfiguration, jwt, data)
 }
 
+// splits the token, checks its signature and claims
+func jwtDecodeVerified(configuration *config, token []byte) (*jwtParts, error) {
+        jwt, err := jwtTokenSplitDecode(configuration, token)
+        if err != nil {
+                return nil, err
+        }
+        err = jwtVerifyParts(configuration, jwt)
+        if err != nil {
+                return nil, err
+        }
+        return jwt, nil
+}
 
-func Decode(token []byte, data interface{}, opts ...Option) error {
-        jwtConfiguration := assemblyJWTConfig(opts)
-        jwt, err := jwtTokenSplitDecode(token)
+// checks signature and claims of split token
+func jwtVerifyParts(configuration *config, jwt *jwtParts) error {
+        // rejected before any key is looked up
+        err := jwtCheckAlgorithm(configuration, jwt)
+        if err != nil {
+                return err
+        }
+        err = jwtCheckCritical(jwt)
         if err != nil {
                 return err
         }
 
-        // check signature methods in header
-        err = jwtCheckHeader(jwtConfiguration, jwt)
+        // the method is taken from configuration or key provider, never from the header
+        verifier, err := jwtVerifier(configuration, jwt.Header.KeyID)
         if err != nil {
+                if configuration.KeyProvider != nil {
+                        return &ValidationError{Part: "kid", Err: err}
+                }
                 return err
         }
 
-	// work with token []byte to avoid redundant copying
-	// validate signature
-	headerAndPayload := token[:bytes.LastIndex(token, []byte("."))]
-	err = jwtSignatureValidation(jwtConfiguration, jwt.Signature, headerAndPayload)
-	if err != nil {
-		return err
-	}
+        // check signature methods in header
+        v := newValidation(configuration)
+        jwtCheckHeader(configuration, verifier, jwt, v)
+
+	// validate signature, it can't be checked by the other method
+        if v.proceed() && jwt.Header.Algorithm == verifier.Algorithm() {
+                err = verifier.Verify(jwt.SigningInput, jwt.Signature)
+                if err != nil {
+                        v.add("signature", err, nil)
+                }
+        }
 
+        jwtValidatePayload(configuration, jwt, v)
+        return v.err()
+}
+
+// validates claims of authenticated payload
+func jwtValidatePayload(configuration *config, jwt *jwtParts, v *validation) {
         // check expire time
-        err = jwtIsAlreadyExpired(jwt)
-	if err != nil {
-                return err
+        if v.proceed() {
+                err := jwtIsAlreadyExpired(configuration, jwt)
+                if err != nil {
+                        v.add("exp", err, nil)
+                }
         }
 
+        // check the rest of registered claims
+        jwtValidateClaims(configuration, &jwt.Payload.registeredClaims, v)
+
+        if v.proceed() {
+                err := jwtCheckRevocation(configuration, &jwt.Payload.registeredClaims)
+                if err != nil {
+                        v.add("jti", err, nil)
+                }
+        }
+}
+
+// user data is "d" or the whole claim set for flat claims
+func jwtUserData(configuration *config, jwt *jwtParts) json.RawMessage {
+        if configuration.FlatClaims {
+                return jwt.RawPayload
+        }
+        return jwt.Payload.Data
+}
+
+func jwtExtractData(configuration *config, jwt *jwtParts, data interface{}) error {
 	// extract data
 	// Для анмаршалинга нужна "схема Json", то есть просто так jsonned interface{} -> interface{}
 	// вроде бы нельзя сконвертировать. Поэтому я и заменил Payload.Data -> json.RawMessage
-	err = json.Unmarshal(jwt.Payload.Data, data)
+	err := json.Unmarshal(jwtUserData(configuration, jwt), data)
 	if err != nil {
-		return ErrInvalidToken
+		return &ValidationError{Part: "payload", Err: ErrInvalidToken, Cause: err}
 	}
 	return nil
 }
 
 
-// To mock time in tests
-var timeFunc = time.Now
+// nil clock means time.Now, the clock can be mocked in tests
+func clockNow(clock func() time.Time) time.Time {
+        if clock == nil {
+                return time.Now()
+        }
+        return clock()
+}
@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyProvider resolves the Verifier for "kid" header of a token.
+// It returns ErrUnknownKeyID if there is no such key.
+type KeyProvider interface {
+	Verifier(kid string) (Verifier, error)
+}
+
+type keySetEntry struct {
+	verifier Verifier
+	retireAt *time.Time
+}
+
+// KeySet is an in-memory KeyProvider for key rotation: new tokens are signed with
+// the newest key while tokens signed with the retiring keys keep verifying.
+type KeySet struct {
+	// Clock is the source of current time for retirement, nil means time.Now
+	Clock func() time.Time
+
+	mu   sync.RWMutex
+	keys map[string]keySetEntry
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: map[string]keySetEntry{}}
+}
+
+// Add adds an active key (or replaces the key with the same kid)
+func (s *KeySet) Add(kid string, v Verifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = keySetEntry{verifier: v}
+}
+
+// Retire keeps the key verifying until at, usually the expiration time of the last
+// token signed with it
+func (s *KeySet) Retire(kid string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.keys[kid]
+	if !ok {
+		return
+	}
+	entry.retireAt = &at
+	s.keys[kid] = entry
+}
+
+// Remove drops the key immediately
+func (s *KeySet) Remove(kid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, kid)
+}
+
+func (s *KeySet) Verifier(kid string) (Verifier, error) {
+	s.mu.RLock()
+	entry, ok := s.keys[kid]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if entry.retireAt != nil && clockNow(s.Clock).After(*entry.retireAt) {
+		return nil, ErrUnknownKeyID
+	}
+	return entry.verifier, nil
+}
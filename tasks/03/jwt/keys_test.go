@@ -0,0 +1,53 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRotation(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time {
+		return now
+	}
+
+	oldKey, err := NewVerifier(HS256, []byte("old-secret"))
+	require.NoError(t, err)
+	newKey, err := NewVerifier(ES256, mustPrivateKey("testdata/ec_p256.pem"))
+	require.NoError(t, err)
+	keys := NewKeySet()
+	keys.Clock = clock
+	keys.Add("2021", oldKey)
+
+	oldToken, err := Encode("old", WithSignMethod(HS256), WithKey([]byte("old-secret")), WithKeyID("2021"), WithTTL(time.Hour), WithClock(clock))
+	require.NoError(t, err)
+
+	// rotation: new tokens use the new key, old ones keep verifying until they expire
+	keys.Add("2022", newKey)
+	keys.Retire("2021", now.Add(time.Hour))
+	newToken, err := Encode("new", WithSignMethod(ES256), WithPrivateKey(mustPrivateKey("testdata/ec_p256.pem")), WithKeyID("2022"), WithTTL(time.Hour), WithClock(clock))
+	require.NoError(t, err)
+
+	var data string
+	require.NoError(t, Decode(oldToken, &data, WithKeyProvider(keys), WithClock(clock)))
+	require.Equal(t, "old", data)
+	require.NoError(t, Decode(newToken, &data, WithKeyProvider(keys), WithClock(clock)))
+	require.Equal(t, "new", data)
+
+	now = now.Add(2 * time.Hour)
+	require.ErrorIs(t, Decode(oldToken, &data, WithKeyProvider(keys), WithClock(clock)), ErrUnknownKeyID)
+
+	// kid is bound to its key, swapping it does not help
+	forged, err := Encode("forged", WithSignMethod(HS256), WithKey([]byte("old-secret")), WithKeyID("2022"))
+	require.NoError(t, err)
+	require.ErrorIs(t, Decode(forged, &data, WithKeyProvider(keys), WithClock(clock)), ErrSignMethodMismatched)
+
+	keys.Remove("2022")
+	require.ErrorIs(t, Decode(newToken, &data, WithKeyProvider(keys), WithClock(clock)), ErrUnknownKeyID)
+
+	noKeyID, err := Encode("no kid", WithSignMethod(HS256), WithKey([]byte("old-secret")))
+	require.NoError(t, err)
+	require.ErrorIs(t, Decode(noKeyID, &data, WithKeyProvider(keys), WithClock(clock)), ErrUnknownKeyID)
+}
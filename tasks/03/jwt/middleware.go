@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const bearerScheme = "Bearer"
+
+type claimsKey struct{}
+
+type userIDKey struct{}
+
+// Authenticator is net/http middleware (usable with chi's Use) which verifies the Bearer
+// token of the request with DecodeClaims and the options, and puts the claims and the user ID
+// ("sub") into the request context. Errors are reported as described in RFC 6750, section 3.
+func Authenticator(opts ...Option) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			scheme, token := req.Header.Get("Authorization"), ""
+			if i := strings.IndexByte(scheme, ' '); i >= 0 {
+				scheme, token = scheme[:i], strings.TrimSpace(scheme[i+1:])
+			}
+			if !strings.EqualFold(scheme, bearerScheme) {
+				// no error code when the request has no bearer authentication information,
+				// including credentials of another scheme
+				rw.Header().Set("WWW-Authenticate", bearerScheme)
+				http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			if token == "" {
+				bearerError(rw, http.StatusBadRequest, "invalid_request", "bearer token expected")
+				return
+			}
+
+			claims, err := DecodeClaims([]byte(token), opts...)
+			if errors.Is(err, ErrTokenExpired) {
+				bearerError(rw, http.StatusUnauthorized, "invalid_token", "token expired")
+				return
+			}
+			if err != nil {
+				bearerError(rw, http.StatusUnauthorized, "invalid_token", "invalid token")
+				return
+			}
+
+			ctx := ContextWithClaims(req.Context(), claims)
+			if claims.Subject != "" {
+				ctx = ContextWithUserID(ctx, claims.Subject)
+			}
+			next.ServeHTTP(rw, req.WithContext(ctx))
+		})
+	}
+}
+
+func bearerError(rw http.ResponseWriter, status int, code string, description string) {
+	rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="%s", error_description="%s"`, code, description))
+	http.Error(rw, description, status)
+}
+
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*Claims, error) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	if !ok {
+		return nil, fmt.Errorf("no claims in context")
+	}
+	return claims, nil
+}
+
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func UserIDFromContext(ctx context.Context) (string, error) {
+	userID, ok := ctx.Value(userIDKey{}).(string)
+	if !ok {
+		return "", fmt.Errorf("no user id in context")
+	}
+	return userID, nil
+}
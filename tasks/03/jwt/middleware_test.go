@@ -0,0 +1,89 @@
+package jwt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator(t *testing.T) {
+	now := time.Unix(1516239022, 0)
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key")), WithIssuer("auth"), WithClock(func() time.Time {
+		return now
+	})}
+	valid, err := Encode(map[string]string{"role": "admin"}, append(opts, WithSubject("user-1"), WithTTL(time.Hour))...)
+	require.NoError(t, err)
+	noSubject, err := Encode("data", opts...)
+	require.NoError(t, err)
+	expired, err := Encode("data", append(opts, WithSubject("user-1"), WithExpires(now.Add(time.Second)))...)
+	require.NoError(t, err)
+	foreign, err := Encode("data", WithSignMethod(HS256), WithKey([]byte("secret-key")), WithIssuer("evil"))
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(Authenticator(opts...))
+	r.Get("/info", func(rw http.ResponseWriter, req *http.Request) {
+		claims, err := ClaimsFromContext(req.Context())
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		uid, err := UserIDFromContext(req.Context())
+		if err != nil {
+			uid = "anonymous"
+		}
+		_, _ = rw.Write([]byte(fmt.Sprintf("Hello, %s %s", uid, claims.Data)))
+	})
+	s := httptest.NewServer(r)
+	defer s.Close()
+	// the token with exp in a second has expired
+	now = now.Add(2 * time.Second)
+
+	testCases := []struct {
+		Authorization string
+		Status        int
+		Challenge     string
+		Body          string
+	}{
+		{Authorization: "Bearer " + string(valid), Status: http.StatusOK, Body: `Hello, user-1 {"role":"admin"}`},
+		{Authorization: "bearer " + string(noSubject), Status: http.StatusOK, Body: `Hello, anonymous "data"`},
+		{Status: http.StatusUnauthorized, Challenge: `Bearer`},
+		{Authorization: "Basic dXNlcjpwYXNz", Status: http.StatusUnauthorized, Challenge: `Bearer`},
+		{Authorization: "Bearer ", Status: http.StatusBadRequest,
+			Challenge: `Bearer error="invalid_request", error_description="bearer token expected"`},
+		{Authorization: "Bearer " + string(expired), Status: http.StatusUnauthorized,
+			Challenge: `Bearer error="invalid_token", error_description="token expired"`},
+		{Authorization: "Bearer " + string(foreign), Status: http.StatusUnauthorized,
+			Challenge: `Bearer error="invalid_token", error_description="invalid token"`},
+		{Authorization: "Bearer 42", Status: http.StatusUnauthorized,
+			Challenge: `Bearer error="invalid_token", error_description="invalid token"`},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/info", nil)
+			if tc.Authorization != "" {
+				req.Header.Set("Authorization", tc.Authorization)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+			require.Equal(t, tc.Status, resp.StatusCode)
+			require.Equal(t, tc.Challenge, resp.Header.Get("WWW-Authenticate"))
+			if tc.Body != "" {
+				body, err := ioutil.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Equal(t, tc.Body, string(body))
+			}
+		})
+	}
+}
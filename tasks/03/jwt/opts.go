@@ -1,6 +1,9 @@
 package jwt
 
-import "time"
+import (
+	"crypto"
+	"time"
+)
 
 type Option func(*config)
 
@@ -16,6 +19,64 @@ func WithKey(k []byte) Option {
 	}
 }
 
+// WithPrivateKey sets the key for asymmetric sign methods (RS*, ES*, EdDSA), used by Encode
+func WithPrivateKey(k crypto.PrivateKey) Option {
+	return func(c *config) {
+		c.PrivateKey = k
+	}
+}
+
+// WithPublicKey sets the key for asymmetric sign methods (RS*, ES*, EdDSA), used by Decode
+func WithPublicKey(k crypto.PublicKey) Option {
+	return func(c *config) {
+		c.PublicKey = k
+	}
+}
+
+// WithSigner replaces the built-in signature of Encode, sign method and keys are ignored
+func WithSigner(s Signer) Option {
+	return func(c *config) {
+		c.Signer = s
+	}
+}
+
+// WithVerifier replaces the built-in signature check of Decode, sign method and keys are ignored
+func WithVerifier(v Verifier) Option {
+	return func(c *config) {
+		c.Verifier = v
+	}
+}
+
+// WithKeyID sets "kid" header for Encode
+func WithKeyID(kid string) Option {
+	return func(c *config) {
+		c.KeyID = kid
+	}
+}
+
+// WithKeyProvider makes Decode resolve the Verifier by "kid" header of the token,
+// sign method and keys are ignored
+func WithKeyProvider(p KeyProvider) Option {
+	return func(c *config) {
+		c.KeyProvider = p
+	}
+}
+
+// WithEncryption sets JWE key management and content encryption with a 256-bit shared
+// key for EncodeEncrypted and DecodeEncrypted
+func WithEncryption(alg KeyAlgorithm, enc ContentEncryption, key []byte) Option {
+	return func(c *config) {
+		c.Encryption = &encryptionConfig{Algorithm: alg, Encryption: enc, Key: key}
+	}
+}
+
+// WithRevocationStore makes Decode reject tokens whose "jti" is revoked in the store
+func WithRevocationStore(s RevocationStore) Option {
+	return func(c *config) {
+		c.RevocationStore = s
+	}
+}
+
 func WithTTL(ttl time.Duration) Option {
 	return func(c *config) {
 		c.TTL = &ttl
@@ -28,9 +89,200 @@ func WithExpires(t time.Time) Option {
 	}
 }
 
+// WithIssuer sets "iss" for Encode, for Decode the token must have the same "iss"
+func WithIssuer(iss string) Option {
+	return func(c *config) {
+		c.Issuer = iss
+	}
+}
+
+// WithSubject sets "sub" for Encode, for Decode the token must have the same "sub"
+func WithSubject(sub string) Option {
+	return func(c *config) {
+		c.Subject = sub
+	}
+}
+
+// RequireSubject makes Decode reject tokens without "sub"
+func RequireSubject() Option {
+	return func(c *config) {
+		c.RequireSubject = true
+	}
+}
+
+// WithAudience sets "aud" for Encode, for Decode the token "aud" must contain at least one of aud
+func WithAudience(aud ...string) Option {
+	return func(c *config) {
+		c.Audience = aud
+	}
+}
+
+// WithNotBefore sets "nbf" for Encode. Decode always rejects tokens before their "nbf"
+func WithNotBefore(t time.Time) Option {
+	return func(c *config) {
+		c.NotBefore = &t
+	}
+}
+
+// WithIssuedAt sets "iat" for Encode. Decode always rejects tokens with "iat" in the future
+func WithIssuedAt(t time.Time) Option {
+	return func(c *config) {
+		c.IssuedAt = &t
+	}
+}
+
+// WithID sets "jti" for Encode
+func WithID(jti string) Option {
+	return func(c *config) {
+		c.ID = jti
+	}
+}
+
+// WithFlatClaims drops the {"d": ...} wrapper: Encode marshals data (struct or map) as
+// the top-level claim set merged with registered claims, Decode unmarshals the whole
+// claim set into data. Without it the wrapped format is used.
+func WithFlatClaims() Option {
+	return func(c *config) {
+		c.FlatClaims = true
+	}
+}
+
+// WithAllErrors makes Decode go on after a failed check and report every failed check
+// in one *ValidationError. Malformed tokens and unknown keys still stop the validation.
+func WithAllErrors() Option {
+	return func(c *config) {
+		c.AllErrors = true
+	}
+}
+
+// WithLeeway tolerates clock skew between hosts: exp, nbf and iat are checked with
+// d of allowance
+func WithLeeway(d time.Duration) Option {
+	return func(c *config) {
+		c.Leeway = d
+	}
+}
+
+// WithClock sets the source of current time for this call (time.Now by default)
+func WithClock(clock func() time.Time) Option {
+	return func(c *config) {
+		c.Clock = clock
+	}
+}
+
+// WithAllowedAlgorithms makes Decode reject tokens whose "alg" header is not one of methods
+// before any key is looked up. "none" is always rejected.
+func WithAllowedAlgorithms(methods ...SignMethod) Option {
+	return func(c *config) {
+		c.AllowedAlgorithms = methods
+	}
+}
+
+// WithMaxTokenSize makes Decode reject tokens longer than size bytes before parsing them
+func WithMaxTokenSize(size int) Option {
+	return func(c *config) {
+		c.MaxTokenSize = size
+	}
+}
+
+// WithStrictJSON makes Decode reject header and payload with duplicate keys, data after
+// the JSON value and non-canonical base64
+func WithStrictJSON() Option {
+	return func(c *config) {
+		c.StrictJSON = true
+	}
+}
+
+// WithType sets the "typ" header, e.g. "at+jwt" for access tokens (RFC 9068); "JWT" by default
+func WithType(typ string) Option {
+	return func(c *config) {
+		c.Type = typ
+	}
+}
+
+// WithContentType sets the "cty" header
+func WithContentType(cty string) Option {
+	return func(c *config) {
+		c.ContentType = cty
+	}
+}
+
+// WithX509Thumbprint sets the "x5t" header, see X509Thumbprint
+func WithX509Thumbprint(x5t string) Option {
+	return func(c *config) {
+		c.X509Thumbprint = x5t
+	}
+}
+
+// WithHeader adds an application-specific header parameter, value is marshalled to JSON.
+// Parameters which have their own options can not be set this way.
+func WithHeader(name string, value interface{}) Option {
+	return func(c *config) {
+		if c.ExtraHeaders == nil {
+			c.ExtraHeaders = map[string]interface{}{}
+		}
+		c.ExtraHeaders[name] = value
+	}
+}
+
+// WithAllowedTypes sets "typ" values accepted by Decode (compared as media types,
+// "" allows tokens without "typ"). Only "JWT" is accepted by default.
+func WithAllowedTypes(types ...string) Option {
+	return func(c *config) {
+		c.AllowedTypes = types
+	}
+}
+
+// WithUnencodedPayload makes SignDetached sign the payload as is, without base64
+// ("b64": false, RFC 7797)
+func WithUnencodedPayload() Option {
+	return func(c *config) {
+		c.UnencodedPayload = true
+	}
+}
+
 type config struct {
-	SignMethod SignMethod
-	Key        []byte
-	TTL        *time.Duration
-	Expires    *time.Time
+	SignMethod  SignMethod
+	Key         []byte
+	PrivateKey  crypto.PrivateKey
+	PublicKey   crypto.PublicKey
+	Signer      Signer
+	Verifier    Verifier
+	KeyID       string
+	KeyProvider KeyProvider
+	TTL         *time.Duration
+	Expires     *time.Time
+
+	Issuer         string
+	Subject        string
+	RequireSubject bool
+	Audience       []string
+	NotBefore      *time.Time
+	IssuedAt       *time.Time
+	ID             string
+
+	FlatClaims bool
+	Encryption *encryptionConfig
+
+	RevocationStore RevocationStore
+	AllErrors       bool
+
+	Leeway time.Duration
+	Clock  func() time.Time
+
+	AllowedAlgorithms []SignMethod
+	MaxTokenSize      int
+	StrictJSON        bool
+
+	Type           string
+	ContentType    string
+	X509Thumbprint string
+	ExtraHeaders   map[string]interface{}
+	AllowedTypes   []string
+
+	UnencodedPayload bool
+}
+
+func (c *config) now() time.Time {
+	return clockNow(c.Clock)
 }
@@ -0,0 +1,51 @@
+package jwt
+
+import "encoding/json"
+
+// UnverifiedToken is a token which is parsed but NOT verified: anybody could have
+// written its header and claims. They may only be used to decide how to verify it
+// (e.g. to pick the key by KeyID or Issuer); Verify does the rest.
+type UnverifiedToken struct {
+	Header Header
+	// registered claims (zero values mean absent claims) and the whole claim set as is
+	Claims    RegisteredClaims
+	RawClaims json.RawMessage
+	// what the signature is computed over: base64 header and payload joined by "."
+	SigningInput []byte
+	Signature    []byte
+
+	jwt *jwtParts
+}
+
+// Parse splits and decodes the token without checking its signature or claims.
+// Parsing options (WithMaxTokenSize, WithStrictJSON) are applied here, other options are ignored.
+func Parse(token []byte, opts ...Option) (*UnverifiedToken, error) {
+	jwt, err := jwtTokenSplitDecode(assemblyJWTConfig(opts), token)
+	if err != nil {
+		return nil, err
+	}
+	// copies: the exported fields may be changed, Verify uses the parsed token itself
+	return &UnverifiedToken{
+		Header:       jwt.Header.clone(),
+		Claims:       jwt.Payload.registeredClaims.export(),
+		RawClaims:    append(json.RawMessage(nil), jwt.RawPayload...),
+		SigningInput: append([]byte(nil), jwt.SigningInput...),
+		Signature:    append([]byte(nil), jwt.Signature...),
+		jwt:          jwt,
+	}, nil
+}
+
+// Verify checks the signature and claims of the token with the options, as Decode does,
+// and returns verified claims
+func (t *UnverifiedToken) Verify(opts ...Option) (*Claims, error) {
+	jwtConfiguration := assemblyJWTConfig(opts)
+	err := jwtVerifyParts(jwtConfiguration, t.jwt)
+	if err != nil {
+		return nil, err
+	}
+	return &Claims{
+		// not t.Claims: those are exported and could be changed after Parse
+		RegisteredClaims: t.jwt.Payload.registeredClaims.export(),
+		Data:             jwtUserData(jwtConfiguration, t.jwt),
+	}, nil
+}
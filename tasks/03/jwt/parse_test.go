@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndVerify(t *testing.T) {
+	partnerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	// a gateway picks the verification options by the unverified issuer
+	issuers := map[string][]Option{
+		"auth":    {WithSignMethod(HS256), WithKey([]byte("secret-key")), WithIssuer("auth")},
+		"partner": {WithSignMethod(ES256), WithPublicKey(&partnerKey.PublicKey), WithIssuer("partner")},
+	}
+	own, err := Encode("own", issuers["auth"]...)
+	require.NoError(t, err)
+	partner, err := Encode("partner", WithSignMethod(ES256), WithPrivateKey(partnerKey), WithIssuer("partner"), WithKeyID("p-1"))
+	require.NoError(t, err)
+
+	for token, data := range map[string]string{string(own): `"own"`, string(partner): `"partner"`} {
+		parsed, err := Parse([]byte(token))
+		require.NoError(t, err)
+		opts, ok := issuers[parsed.Claims.Issuer]
+		require.True(t, ok)
+		require.Equal(t, parsed.Header.Algorithm, assemblyJWTConfig(opts).SignMethod)
+		require.Equal(t, token[:len(parsed.SigningInput)], string(parsed.SigningInput))
+
+		claims, err := parsed.Verify(opts...)
+		require.NoError(t, err)
+		require.JSONEq(t, data, string(claims.Data))
+	}
+
+	parsed, err := Parse(partner)
+	require.NoError(t, err)
+	require.Equal(t, Header{Algorithm: ES256, Type: "JWT", KeyID: "p-1"}, parsed.Header)
+	require.JSONEq(t, `{"d":"partner","iss":"partner"}`, string(parsed.RawClaims))
+	require.Len(t, parsed.Signature, 64)
+
+	// a forged issuer routes the token to the wrong key and verification fails
+	forged, err := Encode("forged", WithSignMethod(HS256), WithKey([]byte("guess")), WithIssuer("auth"))
+	require.NoError(t, err)
+	parsed, err = Parse(forged)
+	require.NoError(t, err)
+	require.Equal(t, "auth", parsed.Claims.Issuer)
+	_, err = parsed.Verify(issuers[parsed.Claims.Issuer]...)
+	require.ErrorIs(t, err, ErrSignatureInvalid)
+	_, err = parsed.Verify(issuers["partner"]...)
+	require.ErrorIs(t, err, ErrSignMethodMismatched)
+
+	// verified claims come from the token, not from the exported unverified ones
+	parsed, err = Parse(own)
+	require.NoError(t, err)
+	parsed.Claims.Issuer = "admin"
+	claims, err := parsed.Verify(issuers["auth"]...)
+	require.NoError(t, err)
+	require.Equal(t, "auth", claims.Issuer)
+
+	// unsecured tokens are parsed but never verified
+	parsed, err = Parse([]byte("eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJkIjoxfQ."))
+	require.NoError(t, err)
+	require.Equal(t, algNone, parsed.Header.Algorithm)
+	require.Equal(t, json.RawMessage(`{"d":1}`), parsed.RawClaims)
+	_, err = parsed.Verify(issuers["auth"]...)
+	require.ErrorIs(t, err, ErrInvalidSignMethod)
+
+	_, err = Parse([]byte("not a token"))
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Equal(t, "token", validationErr.Part)
+	_, err = Parse(own, WithMaxTokenSize(10))
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
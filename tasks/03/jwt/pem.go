@@ -0,0 +1,94 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// ParsePrivateKeyPEM loads a private key from PEM: "RSA PRIVATE KEY" (PKCS#1),
+// "EC PRIVATE KEY" (SEC 1) or "PRIVATE KEY" (PKCS#8, any key type).
+func ParsePrivateKeyPEM(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+		return key, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// ParsePublicKeyPEM loads a public key from PEM: "PUBLIC KEY" (PKIX),
+// "RSA PUBLIC KEY" (PKCS#1) or the key of a "CERTIFICATE".
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+		return key, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+		return key, nil
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// ParseRSAPrivateKeyPEM is ParsePrivateKeyPEM restricted to RSA keys
+func ParseRSAPrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	key, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return rsaKey, nil
+}
+
+// ParseRSAPublicKeyPEM is ParsePublicKeyPEM restricted to RSA keys
+func ParseRSAPublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	key, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return rsaKey, nil
+}
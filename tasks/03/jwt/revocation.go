@@ -0,0 +1,227 @@
+package jwt
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// RevocationStore is a denylist of token IDs ("jti"). Decode consults it when
+// WithRevocationStore is set; tokens without "jti" can not be revoked.
+type RevocationStore interface {
+	// Revoke denies the token until exp, its own expiration time (zero means forever)
+	Revoke(jti string, exp time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
+func jwtCheckRevocation(configuration *config, claims *registeredClaims) error {
+	if configuration.RevocationStore == nil || claims.ID == "" {
+		return nil
+	}
+	revoked, err := configuration.RevocationStore.IsRevoked(claims.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// expired entries are scanned for at most once per interval, not on every Revoke
+const revocationGCInterval = time.Minute
+
+// MemoryRevocationStore keeps revoked IDs in memory, an entry is dropped once the token
+// expires by itself (IsRevoked drops it at once, Revoke collects the rest every minute)
+type MemoryRevocationStore struct {
+	// Clock is the source of current time, nil means time.Now
+	Clock func() time.Time
+
+	mu          sync.Mutex
+	revoked     map[string]time.Time
+	collectedAt time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: map[string]time.Time{}}
+}
+
+func (s *MemoryRevocationStore) Revoke(jti string, exp time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := clockNow(s.Clock)
+	if now.Sub(s.collectedAt) >= revocationGCInterval {
+		s.collectGarbage(now)
+	}
+	s.revoked[jti] = exp
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.revoked[jti]
+	if !ok {
+		return false, nil
+	}
+	if isRevocationExpired(exp, clockNow(s.Clock)) {
+		delete(s.revoked, jti)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Len returns the number of entries which are not collected yet
+func (s *MemoryRevocationStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.revoked)
+}
+
+// must be called with s.mu held
+func (s *MemoryRevocationStore) collectGarbage(now time.Time) {
+	for jti, exp := range s.revoked {
+		if isRevocationExpired(exp, now) {
+			delete(s.revoked, jti)
+		}
+	}
+	s.collectedAt = now
+}
+
+func isRevocationExpired(exp time.Time, now time.Time) bool {
+	return !exp.IsZero() && now.After(exp)
+}
+
+type revocationRecord struct {
+	ID      string `json:"jti"`
+	ExpTime int64  `json:"exp,omitempty"`
+}
+
+// FileRevocationStore is MemoryRevocationStore persisted to an append-only file
+// of JSON lines. Expired entries are dropped from the file when it is opened.
+// The clock is the source of current time, nil means time.Now.
+type FileRevocationStore struct {
+	memory *MemoryRevocationStore
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileRevocationStore(path string, clock func() time.Time) (*FileRevocationStore, error) {
+	memory := NewMemoryRevocationStore()
+	memory.Clock = clock
+	if err := loadRevocations(path, memory); err != nil {
+		return nil, err
+	}
+	if err := compactRevocations(path, memory); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRevocationStore{memory: memory, file: file}, nil
+}
+
+func loadRevocations(path string, memory *MemoryRevocationStore) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record revocationRecord
+		// a torn last line after a crash is skipped
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+		// no garbage collection per line, compactRevocations collects once after loading
+		memory.mu.Lock()
+		memory.revoked[record.ID] = revocationExpTime(record.ExpTime)
+		memory.mu.Unlock()
+	}
+	return scanner.Err()
+}
+
+// rewrites the file with live entries only, the new file replaces the old one atomically
+func compactRevocations(path string, memory *MemoryRevocationStore) error {
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	memory.mu.Lock()
+	memory.collectGarbage(clockNow(memory.Clock))
+	for jti, exp := range memory.revoked {
+		if err = writeRevocation(writer, jti, exp); err != nil {
+			break
+		}
+	}
+	memory.mu.Unlock()
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func writeRevocation(writer io.Writer, jti string, exp time.Time) error {
+	record := revocationRecord{ID: jti}
+	if !exp.IsZero() {
+		record.ExpTime = exp.Unix()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(line, '\n'))
+	return err
+}
+
+func revocationExpTime(exp int64) time.Time {
+	if exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(exp, 0)
+}
+
+func (s *FileRevocationStore) Revoke(jti string, exp time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := writeRevocation(s.file, jti, exp); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	return s.memory.Revoke(jti, exp)
+}
+
+func (s *FileRevocationStore) IsRevoked(jti string) (bool, error) {
+	return s.memory.IsRevoked(jti)
+}
+
+func (s *FileRevocationStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
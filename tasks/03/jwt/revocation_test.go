@@ -0,0 +1,107 @@
+package jwt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocation(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time {
+		return now
+	}
+
+	store := NewMemoryRevocationStore()
+	store.Clock = clock
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key")), WithRevocationStore(store), WithClock(clock)}
+	token, err := Encode("data", append(opts, WithID("session-1"), WithTTL(time.Hour))...)
+	require.NoError(t, err)
+	other, err := Encode("data", append(opts, WithID("session-2"), WithTTL(time.Hour))...)
+	require.NoError(t, err)
+	noID, err := Encode("data", opts...)
+	require.NoError(t, err)
+
+	var data string
+	require.NoError(t, Decode(token, &data, opts...))
+
+	require.NoError(t, store.Revoke("session-1", now.Add(time.Hour)))
+	require.ErrorIs(t, Decode(token, &data, opts...), ErrTokenRevoked)
+	require.NoError(t, Decode(other, &data, opts...))
+	require.NoError(t, Decode(noID, &data, opts...))
+	// the store is consulted only with the option
+	require.NoError(t, Decode(token, &data, append(opts[:2:2], WithClock(clock))...))
+
+	// the entry lives as long as the token itself
+	require.NoError(t, store.Revoke("forever", time.Time{}))
+	now = now.Add(2 * time.Hour)
+	require.ErrorIs(t, Decode(token, &data, opts...), ErrTokenExpired)
+	require.NoError(t, store.Revoke("session-3", now.Add(time.Hour)))
+	require.Equal(t, 2, store.Len())
+	revoked, err := store.IsRevoked("forever")
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+// expired entries are not scanned for on every Revoke, only once per interval
+func TestRevocationGarbageInterval(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := NewMemoryRevocationStore()
+	store.Clock = func() time.Time {
+		return now
+	}
+	require.NoError(t, store.Revoke("a", now.Add(time.Second)))
+	now = now.Add(2 * time.Second)
+	require.NoError(t, store.Revoke("b", now.Add(time.Hour)))
+	require.Equal(t, 2, store.Len())
+	now = now.Add(revocationGCInterval)
+	require.NoError(t, store.Revoke("c", now.Add(time.Hour)))
+	require.Equal(t, 2, store.Len())
+	revoked, err := store.IsRevoked("a")
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestFileRevocationStore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time {
+		return now
+	}
+	path := filepath.Join(t.TempDir(), "revoked.jsonl")
+
+	store, err := NewFileRevocationStore(path, clock)
+	require.NoError(t, err)
+	require.NoError(t, store.Revoke("short", now.Add(time.Minute)))
+	require.NoError(t, store.Revoke("long", now.Add(time.Hour)))
+	require.NoError(t, store.Revoke("forever", time.Time{}))
+	require.NoError(t, store.Close())
+
+	// a torn line after a crash does not break loading
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"jti":"tor`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	now = now.Add(10 * time.Minute)
+	store, err = NewFileRevocationStore(path, clock)
+	require.NoError(t, err)
+	defer func() {
+		_ = store.Close()
+	}()
+	for jti, expected := range map[string]bool{"short": false, "long": true, "forever": true, "unknown": false} {
+		revoked, err := store.IsRevoked(jti)
+		require.NoError(t, err)
+		require.Equal(t, expected, revoked, jti)
+	}
+
+	// expired entries are compacted away on open
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(content), "\n"))
+	require.NotContains(t, string(content), "short")
+}
@@ -0,0 +1,261 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+)
+
+// Signer signs the JWS signing input (base64 header + "." + base64 payload).
+// Built-in methods are created with NewSigner, custom ones (KMS, hardware keys,
+// remote signing services) are passed to Encode with WithSigner.
+type Signer interface {
+	Algorithm() SignMethod
+	Sign(signingInput []byte) ([]byte, error)
+}
+
+// Verifier checks the signature of the JWS signing input.
+// Verify returns ErrSignatureInvalid if the signature does not match.
+type Verifier interface {
+	Algorithm() SignMethod
+	Verify(signingInput []byte, signature []byte) error
+}
+
+var hmacSignHash = map[SignMethod]func() hash.Hash{
+	HS256: sha256.New,
+	HS512: sha512.New,
+}
+
+// RSASSA-PKCS1-v1_5 methods: Encode signs with a private key, Decode needs only the public one
+// (same for ECDSA and EdDSA below)
+var rsaSignHash = map[SignMethod]crypto.Hash{
+	RS256: crypto.SHA256,
+	RS384: crypto.SHA384,
+	RS512: crypto.SHA512,
+}
+
+type ecdsaParams struct {
+	Hash  crypto.Hash
+	Curve elliptic.Curve
+}
+
+// ECDSA methods: each one is bound to its own curve (RFC 7518, section 3.4)
+var ecdsaSignParams = map[SignMethod]ecdsaParams{
+	ES256: {Hash: crypto.SHA256, Curve: elliptic.P256()},
+	ES384: {Hash: crypto.SHA384, Curve: elliptic.P384()},
+	ES512: {Hash: crypto.SHA512, Curve: elliptic.P521()},
+}
+
+// NewSigner creates a built-in Signer. Key is []byte for HS*, *rsa.PrivateKey for RS*,
+// *ecdsa.PrivateKey for ES* and ed25519.PrivateKey for EdDSA.
+func NewSigner(method SignMethod, key interface{}) (Signer, error) {
+	if hashMethod, ok := hmacSignHash[method]; ok {
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return &hmacMethod{method: method, hash: hashMethod, key: secret}, nil
+	}
+	if hashMethod, ok := rsaSignHash[method]; ok {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return &rsaMethod{method: method, hash: hashMethod, privateKey: rsaKey, publicKey: &rsaKey.PublicKey}, nil
+	}
+	if params, ok := ecdsaSignParams[method]; ok {
+		ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok || ecdsaKey.Curve != params.Curve {
+			return nil, ErrInvalidKey
+		}
+		return &ecdsaMethod{method: method, params: params, privateKey: ecdsaKey, publicKey: &ecdsaKey.PublicKey}, nil
+	}
+	if method == EdDSA {
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok || len(edKey) != ed25519.PrivateKeySize {
+			return nil, ErrInvalidKey
+		}
+		return &ed25519Method{privateKey: edKey, publicKey: edKey.Public().(ed25519.PublicKey)}, nil
+	}
+	return nil, ErrInvalidSignMethod
+}
+
+// NewVerifier creates a built-in Verifier. Key is []byte for HS*, public key of the
+// corresponding type for the asymmetric methods (private key is accepted as well).
+func NewVerifier(method SignMethod, key interface{}) (Verifier, error) {
+	if hashMethod, ok := hmacSignHash[method]; ok {
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return &hmacMethod{method: method, hash: hashMethod, key: secret}, nil
+	}
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+	if hashMethod, ok := rsaSignHash[method]; ok {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return &rsaMethod{method: method, hash: hashMethod, publicKey: rsaKey}, nil
+	}
+	if params, ok := ecdsaSignParams[method]; ok {
+		ecdsaKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecdsaKey.Curve != params.Curve {
+			return nil, ErrInvalidKey
+		}
+		return &ecdsaMethod{method: method, params: params, publicKey: ecdsaKey}, nil
+	}
+	if method == EdDSA {
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok || len(edKey) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKey
+		}
+		return &ed25519Method{publicKey: edKey}, nil
+	}
+	return nil, ErrInvalidSignMethod
+}
+
+type hmacMethod struct {
+	method SignMethod
+	hash   func() hash.Hash
+	key    []byte
+}
+
+func (m *hmacMethod) Algorithm() SignMethod {
+	return m.method
+}
+
+func (m *hmacMethod) Sign(signingInput []byte) ([]byte, error) {
+	mac := hmac.New(m.hash, m.key)
+	mac.Write(signingInput)
+	return mac.Sum(nil), nil
+}
+
+func (m *hmacMethod) Verify(signingInput []byte, signature []byte) error {
+	expected, _ := m.Sign(signingInput)
+	// constant time, so that the MAC can not be guessed byte by byte
+	if !hmac.Equal(signature, expected) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+type rsaMethod struct {
+	method     SignMethod
+	hash       crypto.Hash
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
+
+func (m *rsaMethod) Algorithm() SignMethod {
+	return m.method
+}
+
+func (m *rsaMethod) PublicKey() crypto.PublicKey {
+	return m.publicKey
+}
+
+func (m *rsaMethod) Sign(signingInput []byte) ([]byte, error) {
+	if m.privateKey == nil {
+		return nil, ErrInvalidKey
+	}
+	return rsa.SignPKCS1v15(rand.Reader, m.privateKey, m.hash, digest(m.hash, signingInput))
+}
+
+func (m *rsaMethod) Verify(signingInput []byte, signature []byte) error {
+	if rsa.VerifyPKCS1v15(m.publicKey, m.hash, digest(m.hash, signingInput), signature) != nil {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// JWS stores ECDSA signature as R||S, both zero-padded to the curve size, instead of ASN.1 DER
+type ecdsaMethod struct {
+	method     SignMethod
+	params     ecdsaParams
+	privateKey *ecdsa.PrivateKey
+	publicKey  *ecdsa.PublicKey
+}
+
+func (m *ecdsaMethod) Algorithm() SignMethod {
+	return m.method
+}
+
+func (m *ecdsaMethod) PublicKey() crypto.PublicKey {
+	return m.publicKey
+}
+
+func (m *ecdsaMethod) keySize() int {
+	return (m.params.Curve.Params().BitSize + 7) / 8
+}
+
+func (m *ecdsaMethod) Sign(signingInput []byte) ([]byte, error) {
+	if m.privateKey == nil {
+		return nil, ErrInvalidKey
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, m.privateKey, digest(m.params.Hash, signingInput))
+	if err != nil {
+		return nil, err
+	}
+	size := m.keySize()
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signature, nil
+}
+
+func (m *ecdsaMethod) Verify(signingInput []byte, signature []byte) error {
+	size := m.keySize()
+	if len(signature) != 2*size {
+		return ErrSignatureInvalid
+	}
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(m.publicKey, digest(m.params.Hash, signingInput), r, s) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+type ed25519Method struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+func (m *ed25519Method) Algorithm() SignMethod {
+	return EdDSA
+}
+
+func (m *ed25519Method) PublicKey() crypto.PublicKey {
+	return m.publicKey
+}
+
+func (m *ed25519Method) Sign(signingInput []byte) ([]byte, error) {
+	if m.privateKey == nil {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.Sign(m.privateKey, signingInput), nil
+}
+
+func (m *ed25519Method) Verify(signingInput []byte, signature []byte) error {
+	if !ed25519.Verify(m.publicKey, signingInput, signature) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+func digest(hashMethod crypto.Hash, data []byte) []byte {
+	hasher := hashMethod.New()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
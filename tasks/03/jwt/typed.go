@@ -0,0 +1,38 @@
+package jwt
+
+// TypedClaims are Claims with user data unmarshalled into T
+type TypedClaims[T any] struct {
+	RegisteredClaims
+	Data T
+}
+
+// EncodeAs is Encode with compile-time checked type of user data
+func EncodeAs[T any](data T, opts ...Option) ([]byte, error) {
+	return Encode(data, opts...)
+}
+
+// DecodeAs is Decode which returns user data as T instead of filling interface{}
+func DecodeAs[T any](token []byte, opts ...Option) (T, error) {
+	var data T
+	err := Decode(token, &data, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return data, nil
+}
+
+// DecodeClaimsAs is DecodeClaims with user data unmarshalled into T
+func DecodeClaimsAs[T any](token []byte, opts ...Option) (*TypedClaims[T], error) {
+	jwtConfiguration := assemblyJWTConfig(opts)
+	jwt, err := jwtDecodeVerified(jwtConfiguration, token)
+	if err != nil {
+		return nil, err
+	}
+	claims := &TypedClaims[T]{RegisteredClaims: jwt.Payload.registeredClaims.export()}
+	err = jwtExtractData(jwtConfiguration, jwt, &claims.Data)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
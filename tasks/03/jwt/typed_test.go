@@ -0,0 +1,68 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type profile struct {
+	Name   string   `json:"name"`
+	Age    int      `json:"age"`
+	Scopes []string `json:"scopes"`
+}
+
+func TestTypedDecode(t *testing.T) {
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key"))}
+	expected := profile{Name: "Dmitrii", Age: 30, Scopes: []string{"read", "write"}}
+
+	token, err := EncodeAs(expected, append(opts, WithSubject("user-1"), WithExpires(time.Now().Add(time.Hour).Truncate(time.Second)))...)
+	require.NoError(t, err)
+
+	data, err := DecodeAs[profile](token, opts...)
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+
+	claims, err := DecodeClaimsAs[profile](token, opts...)
+	require.NoError(t, err)
+	require.Equal(t, expected, claims.Data)
+	require.Equal(t, "user-1", claims.Subject)
+	require.False(t, claims.ExpiresAt.IsZero())
+
+	// numbers keep their type, unlike map[string]interface{}
+	age, err := DecodeAs[map[string]int](mustEncode(t, map[string]int{"age": 30}, opts...), opts...)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"age": 30}, age)
+
+	data, err = DecodeAs[profile](token, WithSignMethod(HS256), WithKey([]byte("other-key")))
+	require.ErrorIs(t, err, ErrSignatureInvalid)
+	require.Equal(t, profile{}, data)
+
+	_, err = DecodeAs[int](token, opts...)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	_, err = DecodeClaimsAs[int](token, opts...)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestTypedFlatClaims(t *testing.T) {
+	type userClaims struct {
+		Name    string `json:"name"`
+		Subject string `json:"sub"`
+	}
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key")), WithFlatClaims()}
+	token, err := EncodeAs(userClaims{Name: "Ivan"}, append(opts, WithSubject("user-1"))...)
+	require.NoError(t, err)
+
+	claims, err := DecodeClaimsAs[userClaims](token, opts...)
+	require.NoError(t, err)
+	require.Equal(t, userClaims{Name: "Ivan", Subject: "user-1"}, claims.Data)
+	require.Equal(t, "user-1", claims.Subject)
+}
+
+func mustEncode(t *testing.T, data interface{}, opts ...Option) []byte {
+	t.Helper()
+	token, err := Encode(data, opts...)
+	require.NoError(t, err)
+	return token
+}
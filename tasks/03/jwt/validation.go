@@ -0,0 +1,87 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+)
+
+// ValidationError describes a failed check of a token. It wraps one of the sentinel errors,
+// so errors.Is(err, ErrTokenExpired) keeps working, and keeps the underlying cause (base64,
+// JSON) which the sentinel hides. With WithAllErrors the checks go on after the first failure
+// and the rest of failed checks are collected in Others.
+type ValidationError struct {
+	// segment ("token", "header", "payload", "signature"; for JWE "encrypted_key", "iv",
+	// "ciphertext", "tag") or claim ("exp", "iss", "jti", ...)
+	Part   string
+	Err    error
+	Cause  error
+	Others []*ValidationError
+}
+
+func (e *ValidationError) Error() string {
+	var msg strings.Builder
+	e.write(&msg)
+	for _, other := range e.Others {
+		msg.WriteString("; ")
+		other.write(&msg)
+	}
+	return msg.String()
+}
+
+func (e *ValidationError) write(msg *strings.Builder) {
+	msg.WriteString(e.Part)
+	msg.WriteString(": ")
+	msg.WriteString(e.Err.Error())
+	if e.Cause != nil {
+		msg.WriteString(": ")
+		msg.WriteString(e.Cause.Error())
+	}
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the cause and the other failed checks as well
+func (e *ValidationError) Is(target error) bool {
+	if e.Cause != nil && errors.Is(e.Cause, target) {
+		return true
+	}
+	for _, other := range e.Others {
+		if errors.Is(other, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// collects failed checks of one token
+type validation struct {
+	all    bool
+	result *ValidationError
+}
+
+func newValidation(configuration *config) *validation {
+	return &validation{all: configuration.AllErrors}
+}
+
+func (v *validation) add(part string, err error, cause error) {
+	failed := &ValidationError{Part: part, Err: err, Cause: cause}
+	if v.result == nil {
+		v.result = failed
+		return
+	}
+	v.result.Others = append(v.result.Others, failed)
+}
+
+// false after the first failed check unless all errors are collected
+func (v *validation) proceed() bool {
+	return v.all || v.result == nil
+}
+
+func (v *validation) err() error {
+	if v.result == nil {
+		return nil
+	}
+	return v.result
+}
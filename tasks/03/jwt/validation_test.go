@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationError(t *testing.T) {
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret-key"))}
+	token, err := Encode("data", append(opts, WithIssuer("auth"), WithExpires(time.Now().Add(time.Hour)))...)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name  string
+		Token string
+		Opts  []Option
+		Part  string
+		Err   error
+		Cause bool
+	}{
+		{Name: "segments", Token: "a.b", Part: "token", Err: ErrInvalidToken},
+		{Name: "header base64", Token: "!!!.e30.", Part: "header", Err: ErrInvalidToken, Cause: true},
+		{Name: "header json", Token: "bm90IGpzb24.e30.", Part: "header", Err: ErrInvalidToken, Cause: true},
+		{Name: "payload json", Token: "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.bm90IGpzb24.", Part: "payload", Err: ErrInvalidToken, Cause: true},
+		{Name: "alg", Token: string(token), Opts: []Option{WithSignMethod(HS512), WithKey([]byte("secret-key"))},
+			Part: "alg", Err: ErrSignMethodMismatched},
+		{Name: "signature", Token: string(token), Opts: []Option{WithSignMethod(HS256), WithKey([]byte("other-key"))},
+			Part: "signature", Err: ErrSignatureInvalid},
+		{Name: "issuer", Token: string(token), Opts: append(opts, WithIssuer("other")), Part: "iss", Err: ErrInvalidIssuer},
+		{Name: "kid", Token: string(token), Opts: []Option{WithKeyProvider(NewKeySet())}, Part: "kid", Err: ErrUnknownKeyID},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var data string
+			err := Decode([]byte(tc.Token), &data, append(opts, tc.Opts...)...)
+			require.ErrorIs(t, err, tc.Err)
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			require.Equal(t, tc.Part, validationErr.Part)
+			require.Equal(t, tc.Err, validationErr.Err)
+			require.Equal(t, tc.Cause, validationErr.Cause != nil)
+			require.Empty(t, validationErr.Others)
+		})
+	}
+
+	var syntaxErr *json.SyntaxError
+	err = Decode([]byte("bm90IGpzb24.e30."), new(string), opts...)
+	require.True(t, errors.As(err.(*ValidationError).Cause, &syntaxErr))
+	require.True(t, errors.Is(Decode([]byte("!!!.e30."), new(string), opts...), base64.CorruptInputError(0)))
+}
+
+func TestValidationAllErrors(t *testing.T) {
+	token, err := Encode("data", WithSignMethod(HS256), WithKey([]byte("secret-key")),
+		WithIssuer("auth"), WithNotBefore(time.Unix(300, 0)), WithExpires(time.Unix(250, 0)), WithClock(func() time.Time {
+			return time.Unix(200, 0)
+		}))
+	require.NoError(t, err)
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("other-key")), WithIssuer("other"), WithSubject("user-1"),
+		WithClock(func() time.Time {
+			return time.Unix(400, 0)
+		})}
+
+	// stops at the first failed check by default
+	err = Decode(token, new(string), opts...)
+	require.EqualError(t, err, "signature: signature invalid")
+	require.NotErrorIs(t, err, ErrTokenExpired)
+
+	err = Decode(token, new(string), append(opts, WithAllErrors())...)
+	require.EqualError(t, err, "signature: signature invalid; exp: token expired; iss: invalid issuer; sub: invalid subject")
+	for _, sentinel := range []error{ErrSignatureInvalid, ErrTokenExpired, ErrInvalidIssuer, ErrInvalidSubject} {
+		require.ErrorIs(t, err, sentinel)
+	}
+	require.NotErrorIs(t, err, ErrTokenNotValidYet)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Others, 3)
+	require.Equal(t, "exp", validationErr.Others[0].Part)
+}